@@ -16,13 +16,14 @@ type Node struct {
 }
 
 type Ast struct {
-	AstType string
-	Nodes   []Node
+	AstType    string
+	ReturnType string
+	Nodes      []Node
 }
 
 func main() {
 	exprs := []Ast{
-		{"Expr", []Node{
+		{"Expr", "Value", []Node{
 			{"BinaryExpr", []Arg{
 				{"Left", "Expr"},
 				{"Operator", "Token"},
@@ -31,6 +32,7 @@ func main() {
 				{"Expr", "Expr"},
 			}}, {"LiteralExpr", []Arg{
 				{"Value", "any"},
+				{"Token", "Token"},
 			}}, {"UnaryExpr", []Arg{
 				{"Operator", "Token"},
 				{"Expr", "Expr"},
@@ -47,12 +49,57 @@ func main() {
 				{"Callee", "Expr"},
 				{"Paren", "Token"},
 				{"Arguments", "[]Expr"},
+			}}, {"GetExpr", []Arg{
+				{"Object", "Expr"},
+				{"Name", "Token"},
+			}}, {"SetExpr", []Arg{
+				{"Object", "Expr"},
+				{"Name", "Token"},
+				{"Value", "Expr"},
+			}}, {"CompoundSetExpr", []Arg{
+				{"Object", "Expr"},
+				{"Name", "Token"},
+				{"Operator", "Token"},
+				{"Value", "Expr"},
+			}}, {"ListExpr", []Arg{
+				{"Bracket", "Token"},
+				{"Elements", "[]Expr"},
+			}}, {"MapExpr", []Arg{
+				{"Brace", "Token"},
+				{"Keys", "[]Expr"},
+				{"Values", "[]Expr"},
+			}}, {"IndexExpr", []Arg{
+				{"Object", "Expr"},
+				{"Bracket", "Token"},
+				{"Index", "Expr"},
+			}}, {"IndexSetExpr", []Arg{
+				{"Object", "Expr"},
+				{"Bracket", "Token"},
+				{"Index", "Expr"},
+				{"Value", "Expr"},
+			}}, {"CompoundIndexSetExpr", []Arg{
+				{"Object", "Expr"},
+				{"Bracket", "Token"},
+				{"Index", "Expr"},
+				{"Operator", "Token"},
+				{"Value", "Expr"},
+			}}, {"ThisExpr", []Arg{
+				{"Keyword", "Token"},
+			}}, {"SuperExpr", []Arg{
+				{"Keyword", "Token"},
+				{"Method", "Token"},
+			}}, {"StringifyExpr", []Arg{
+				{"Part", "Token"},
+				{"Expr", "Expr"},
+			}}, {"FunctionExpr", []Arg{
+				{"Function", "*Function"},
 			}},
 		}},
-		{"Stmt", []Node{
+		{"Stmt", "any", []Node{
 			{"ExprStmt", []Arg{
 				{"Expr", "Expr"},
 			}}, {"PrintStmt", []Arg{
+				{"Keyword", "Token"},
 				{"Expr", "Expr"},
 			}}, {"VarDecl", []Arg{
 				{"Name", "Token"},
@@ -66,12 +113,32 @@ func main() {
 			}}, {"WhileStmt", []Arg{
 				{"Condition", "Expr"},
 				{"Body", "Stmt"},
+			}}, {"ForStmt", []Arg{
+				{"Initializer", "Stmt"},
+				{"Condition", "Expr"},
+				{"Increment", "Expr"},
+				{"Body", "Stmt"},
+			}}, {"ForInStmt", []Arg{
+				{"Name", "Token"},
+				{"In", "Token"},
+				{"Iterable", "Expr"},
+				{"Body", "Stmt"},
+			}}, {"BreakStmt", []Arg{
+				{"Keyword", "Token"},
+			}}, {"ContinueStmt", []Arg{
+				{"Keyword", "Token"},
 			}}, {"Function", []Arg{
 				{"Name", "Token"},
 				{"Params", "[]Token"},
 				{"Body", "[]Stmt"},
-			},
-			},
+			}}, {"ReturnStmt", []Arg{
+				{"Keyword", "Token"},
+				{"Value", "Expr"},
+			}}, {"ClassStmt", []Arg{
+				{"Name", "Token"},
+				{"Superclass", "*VariableExpr"},
+				{"Methods", "[]*Function"},
+			}},
 		}}}
 	tmplSrc, err := os.ReadFile("../generateast/ast.go.tmpl")
 	if err != nil {
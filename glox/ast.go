@@ -3,7 +3,8 @@ package glox
 //go:generate go run ../generateast/generateast.go
 
 type Expr interface {
-	Accept(visitor ExprVisitor) (any, error)
+	Accept(visitor ExprVisitor) (Value, error)
+	Span() Span
 } 
 
 type BinaryExpr struct { 
@@ -12,156 +13,581 @@ type BinaryExpr struct {
 	Right Expr
 }
 
-func (e BinaryExpr) Accept(visitor ExprVisitor) (any, error) {
+func (e *BinaryExpr) Accept(visitor ExprVisitor) (Value, error) {
 	return visitor.VisitBinaryExpr(e)
 }
 
+func (e *BinaryExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Left), spanOf(e.Operator), spanOf(e.Right))
+}
+
 type GroupingExpr struct { 
 	Expr Expr
 }
 
-func (e GroupingExpr) Accept(visitor ExprVisitor) (any, error) {
+func (e *GroupingExpr) Accept(visitor ExprVisitor) (Value, error) {
 	return visitor.VisitGroupingExpr(e)
 }
 
+func (e *GroupingExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Expr))
+}
+
 type LiteralExpr struct { 
 	Value any
+	Token Token
 }
 
-func (e LiteralExpr) Accept(visitor ExprVisitor) (any, error) {
+func (e *LiteralExpr) Accept(visitor ExprVisitor) (Value, error) {
 	return visitor.VisitLiteralExpr(e)
 }
 
+func (e *LiteralExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Value), spanOf(e.Token))
+}
+
 type UnaryExpr struct { 
 	Operator Token
 	Expr Expr
 }
 
-func (e UnaryExpr) Accept(visitor ExprVisitor) (any, error) {
+func (e *UnaryExpr) Accept(visitor ExprVisitor) (Value, error) {
 	return visitor.VisitUnaryExpr(e)
 }
 
+func (e *UnaryExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Operator), spanOf(e.Expr))
+}
+
 type VariableExpr struct { 
 	Name Token
 }
 
-func (e VariableExpr) Accept(visitor ExprVisitor) (any, error) {
+func (e *VariableExpr) Accept(visitor ExprVisitor) (Value, error) {
 	return visitor.VisitVariableExpr(e)
 }
 
+func (e *VariableExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Name))
+}
+
 type AssignExpr struct { 
 	Name Token
 	Value Expr
 }
 
-func (e AssignExpr) Accept(visitor ExprVisitor) (any, error) {
+func (e *AssignExpr) Accept(visitor ExprVisitor) (Value, error) {
 	return visitor.VisitAssignExpr(e)
 }
 
+func (e *AssignExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Name), spanOf(e.Value))
+}
+
 type LogicalExpr struct { 
 	Left Expr
 	Operator Token
 	Right Expr
 }
 
-func (e LogicalExpr) Accept(visitor ExprVisitor) (any, error) {
+func (e *LogicalExpr) Accept(visitor ExprVisitor) (Value, error) {
 	return visitor.VisitLogicalExpr(e)
 }
 
+func (e *LogicalExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Left), spanOf(e.Operator), spanOf(e.Right))
+}
+
 type CallExpr struct { 
 	Callee Expr
 	Paren Token
 	Arguments []Expr
 }
 
-func (e CallExpr) Accept(visitor ExprVisitor) (any, error) {
+func (e *CallExpr) Accept(visitor ExprVisitor) (Value, error) {
 	return visitor.VisitCallExpr(e)
 }
 
+func (e *CallExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Callee), spanOf(e.Paren), spanOf(e.Arguments))
+}
+
+type GetExpr struct { 
+	Object Expr
+	Name Token
+}
+
+func (e *GetExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitGetExpr(e)
+}
+
+func (e *GetExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Object), spanOf(e.Name))
+}
+
+type SetExpr struct { 
+	Object Expr
+	Name Token
+	Value Expr
+}
+
+func (e *SetExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitSetExpr(e)
+}
+
+func (e *SetExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Object), spanOf(e.Name), spanOf(e.Value))
+}
+
+type CompoundSetExpr struct { 
+	Object Expr
+	Name Token
+	Operator Token
+	Value Expr
+}
+
+func (e *CompoundSetExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitCompoundSetExpr(e)
+}
+
+func (e *CompoundSetExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Object), spanOf(e.Name), spanOf(e.Operator), spanOf(e.Value))
+}
+
+type ListExpr struct { 
+	Bracket Token
+	Elements []Expr
+}
+
+func (e *ListExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitListExpr(e)
+}
+
+func (e *ListExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Bracket), spanOf(e.Elements))
+}
+
+type MapExpr struct { 
+	Brace Token
+	Keys []Expr
+	Values []Expr
+}
+
+func (e *MapExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitMapExpr(e)
+}
+
+func (e *MapExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Brace), spanOf(e.Keys), spanOf(e.Values))
+}
+
+type IndexExpr struct { 
+	Object Expr
+	Bracket Token
+	Index Expr
+}
+
+func (e *IndexExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitIndexExpr(e)
+}
+
+func (e *IndexExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Object), spanOf(e.Bracket), spanOf(e.Index))
+}
+
+type IndexSetExpr struct { 
+	Object Expr
+	Bracket Token
+	Index Expr
+	Value Expr
+}
+
+func (e *IndexSetExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitIndexSetExpr(e)
+}
+
+func (e *IndexSetExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Object), spanOf(e.Bracket), spanOf(e.Index), spanOf(e.Value))
+}
+
+type CompoundIndexSetExpr struct { 
+	Object Expr
+	Bracket Token
+	Index Expr
+	Operator Token
+	Value Expr
+}
+
+func (e *CompoundIndexSetExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitCompoundIndexSetExpr(e)
+}
+
+func (e *CompoundIndexSetExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Object), spanOf(e.Bracket), spanOf(e.Index), spanOf(e.Operator), spanOf(e.Value))
+}
+
+type ThisExpr struct { 
+	Keyword Token
+}
+
+func (e *ThisExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitThisExpr(e)
+}
+
+func (e *ThisExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Keyword))
+}
+
+type SuperExpr struct { 
+	Keyword Token
+	Method Token
+}
+
+func (e *SuperExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitSuperExpr(e)
+}
+
+func (e *SuperExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Keyword), spanOf(e.Method))
+}
+
+type StringifyExpr struct { 
+	Part Token
+	Expr Expr
+}
+
+func (e *StringifyExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitStringifyExpr(e)
+}
+
+func (e *StringifyExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Part), spanOf(e.Expr))
+}
+
+type FunctionExpr struct { 
+	Function *Function
+}
+
+func (e *FunctionExpr) Accept(visitor ExprVisitor) (Value, error) {
+	return visitor.VisitFunctionExpr(e)
+}
+
+func (e *FunctionExpr) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Function))
+}
+
 type ExprVisitor interface { 
-	VisitBinaryExpr(expr BinaryExpr) (any, error)
-	VisitGroupingExpr(expr GroupingExpr) (any, error)
-	VisitLiteralExpr(expr LiteralExpr) (any, error)
-	VisitUnaryExpr(expr UnaryExpr) (any, error)
-	VisitVariableExpr(expr VariableExpr) (any, error)
-	VisitAssignExpr(expr AssignExpr) (any, error)
-	VisitLogicalExpr(expr LogicalExpr) (any, error)
-	VisitCallExpr(expr CallExpr) (any, error)
+	VisitBinaryExpr(expr *BinaryExpr) (Value, error)
+	VisitGroupingExpr(expr *GroupingExpr) (Value, error)
+	VisitLiteralExpr(expr *LiteralExpr) (Value, error)
+	VisitUnaryExpr(expr *UnaryExpr) (Value, error)
+	VisitVariableExpr(expr *VariableExpr) (Value, error)
+	VisitAssignExpr(expr *AssignExpr) (Value, error)
+	VisitLogicalExpr(expr *LogicalExpr) (Value, error)
+	VisitCallExpr(expr *CallExpr) (Value, error)
+	VisitGetExpr(expr *GetExpr) (Value, error)
+	VisitSetExpr(expr *SetExpr) (Value, error)
+	VisitCompoundSetExpr(expr *CompoundSetExpr) (Value, error)
+	VisitListExpr(expr *ListExpr) (Value, error)
+	VisitMapExpr(expr *MapExpr) (Value, error)
+	VisitIndexExpr(expr *IndexExpr) (Value, error)
+	VisitIndexSetExpr(expr *IndexSetExpr) (Value, error)
+	VisitCompoundIndexSetExpr(expr *CompoundIndexSetExpr) (Value, error)
+	VisitThisExpr(expr *ThisExpr) (Value, error)
+	VisitSuperExpr(expr *SuperExpr) (Value, error)
+	VisitStringifyExpr(expr *StringifyExpr) (Value, error)
+	VisitFunctionExpr(expr *FunctionExpr) (Value, error)
 }
 
 type Stmt interface {
 	Accept(visitor StmtVisitor) (any, error)
+	Span() Span
 } 
 
 type ExprStmt struct { 
 	Expr Expr
 }
 
-func (e ExprStmt) Accept(visitor StmtVisitor) (any, error) {
+func (e *ExprStmt) Accept(visitor StmtVisitor) (any, error) {
 	return visitor.VisitExprStmt(e)
 }
 
+func (e *ExprStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Expr))
+}
+
 type PrintStmt struct { 
+	Keyword Token
 	Expr Expr
 }
 
-func (e PrintStmt) Accept(visitor StmtVisitor) (any, error) {
+func (e *PrintStmt) Accept(visitor StmtVisitor) (any, error) {
 	return visitor.VisitPrintStmt(e)
 }
 
+func (e *PrintStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Keyword), spanOf(e.Expr))
+}
+
 type VarDecl struct { 
 	Name Token
 	Initializer Expr
 }
 
-func (e VarDecl) Accept(visitor StmtVisitor) (any, error) {
+func (e *VarDecl) Accept(visitor StmtVisitor) (any, error) {
 	return visitor.VisitVarDecl(e)
 }
 
+func (e *VarDecl) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Name), spanOf(e.Initializer))
+}
+
 type Block struct { 
 	Stmts []Stmt
 }
 
-func (e Block) Accept(visitor StmtVisitor) (any, error) {
+func (e *Block) Accept(visitor StmtVisitor) (any, error) {
 	return visitor.VisitBlock(e)
 }
 
+func (e *Block) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Stmts))
+}
+
 type IfStmt struct { 
 	Condition Expr
 	ThenBranch Stmt
 	ElseBranch Stmt
 }
 
-func (e IfStmt) Accept(visitor StmtVisitor) (any, error) {
+func (e *IfStmt) Accept(visitor StmtVisitor) (any, error) {
 	return visitor.VisitIfStmt(e)
 }
 
+func (e *IfStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Condition), spanOf(e.ThenBranch), spanOf(e.ElseBranch))
+}
+
 type WhileStmt struct { 
 	Condition Expr
 	Body Stmt
 }
 
-func (e WhileStmt) Accept(visitor StmtVisitor) (any, error) {
+func (e *WhileStmt) Accept(visitor StmtVisitor) (any, error) {
 	return visitor.VisitWhileStmt(e)
 }
 
+func (e *WhileStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Condition), spanOf(e.Body))
+}
+
+type ForStmt struct { 
+	Initializer Stmt
+	Condition Expr
+	Increment Expr
+	Body Stmt
+}
+
+func (e *ForStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitForStmt(e)
+}
+
+func (e *ForStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Initializer), spanOf(e.Condition), spanOf(e.Increment), spanOf(e.Body))
+}
+
+type ForInStmt struct { 
+	Name Token
+	In Token
+	Iterable Expr
+	Body Stmt
+}
+
+func (e *ForInStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitForInStmt(e)
+}
+
+func (e *ForInStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Name), spanOf(e.In), spanOf(e.Iterable), spanOf(e.Body))
+}
+
+type BreakStmt struct { 
+	Keyword Token
+}
+
+func (e *BreakStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitBreakStmt(e)
+}
+
+func (e *BreakStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Keyword))
+}
+
+type ContinueStmt struct { 
+	Keyword Token
+}
+
+func (e *ContinueStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitContinueStmt(e)
+}
+
+func (e *ContinueStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Keyword))
+}
+
 type Function struct { 
 	Name Token
 	Params []Token
 	Body []Stmt
 }
 
-func (e Function) Accept(visitor StmtVisitor) (any, error) {
+func (e *Function) Accept(visitor StmtVisitor) (any, error) {
 	return visitor.VisitFunction(e)
 }
 
+func (e *Function) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Name), spanOf(e.Params), spanOf(e.Body))
+}
+
+type ReturnStmt struct { 
+	Keyword Token
+	Value Expr
+}
+
+func (e *ReturnStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitReturnStmt(e)
+}
+
+func (e *ReturnStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Keyword), spanOf(e.Value))
+}
+
+type ClassStmt struct { 
+	Name Token
+	Superclass *VariableExpr
+	Methods []*Function
+}
+
+func (e *ClassStmt) Accept(visitor StmtVisitor) (any, error) {
+	return visitor.VisitClassStmt(e)
+}
+
+func (e *ClassStmt) Span() Span {
+	if e == nil {
+		return Span{}
+	}
+	return joinSpans(spanOf(e.Name), spanOf(e.Superclass), spanOf(e.Methods))
+}
+
 type StmtVisitor interface { 
-	VisitExprStmt(expr ExprStmt) (any, error)
-	VisitPrintStmt(expr PrintStmt) (any, error)
-	VisitVarDecl(expr VarDecl) (any, error)
-	VisitBlock(expr Block) (any, error)
-	VisitIfStmt(expr IfStmt) (any, error)
-	VisitWhileStmt(expr WhileStmt) (any, error)
-	VisitFunction(expr Function) (any, error)
+	VisitExprStmt(expr *ExprStmt) (any, error)
+	VisitPrintStmt(expr *PrintStmt) (any, error)
+	VisitVarDecl(expr *VarDecl) (any, error)
+	VisitBlock(expr *Block) (any, error)
+	VisitIfStmt(expr *IfStmt) (any, error)
+	VisitWhileStmt(expr *WhileStmt) (any, error)
+	VisitForStmt(expr *ForStmt) (any, error)
+	VisitForInStmt(expr *ForInStmt) (any, error)
+	VisitBreakStmt(expr *BreakStmt) (any, error)
+	VisitContinueStmt(expr *ContinueStmt) (any, error)
+	VisitFunction(expr *Function) (any, error)
+	VisitReturnStmt(expr *ReturnStmt) (any, error)
+	VisitClassStmt(expr *ClassStmt) (any, error)
 }
 
@@ -0,0 +1,75 @@
+package glox
+
+type LoxCallable interface {
+	Arity() int
+	Call(i *Interpreter, args []Value) (Value, error)
+	String() string
+}
+
+// returnValue unwinds the interpreter from a return statement up to the
+// function call that is being returned from.
+type returnValue struct {
+	value Value
+}
+
+func (r returnValue) Error() string {
+	return "Can't return from top-level code."
+}
+
+// breakLoop and continueLoop unwind the interpreter from a break or continue
+// statement up to the innermost loop.
+type breakLoop struct{}
+
+func (breakLoop) Error() string {
+	return "Can't use 'break' outside of a loop."
+}
+
+type continueLoop struct{}
+
+func (continueLoop) Error() string {
+	return "Can't use 'continue' outside of a loop."
+}
+
+type LoxFunction struct {
+	declaration   *Function
+	closure       *Enviorment
+	isInitializer bool
+}
+
+// bind returns a copy of the method whose closure defines "this" as the
+// given instance.
+func (f LoxFunction) bind(instance *LoxInstance) LoxFunction {
+	env := &Enviorment{f.closure, map[string]Value{}}
+	env.Put("this", ObjectValue(instance))
+	return LoxFunction{f.declaration, env, f.isInitializer}
+}
+
+func (f LoxFunction) Arity() int {
+	return len(f.declaration.Params)
+}
+
+func (f LoxFunction) Call(i *Interpreter, args []Value) (Value, error) {
+	env := &Enviorment{f.closure, map[string]Value{}}
+	for idx, param := range f.declaration.Params {
+		env.Put(param.Lexme, args[idx])
+	}
+
+	err := i.executeBlock(f.declaration.Body, env)
+	if ret, ok := err.(returnValue); ok {
+		if f.isInitializer {
+			return f.closure.GetAt(0, "this"), nil
+		}
+		return ret.value, nil
+	}
+	if err != nil {
+		return NilValue, err
+	}
+	if f.isInitializer {
+		return f.closure.GetAt(0, "this"), nil
+	}
+	return NilValue, nil
+}
+
+func (f LoxFunction) String() string {
+	return "<fn " + f.declaration.Name.Lexme + ">"
+}
@@ -0,0 +1,256 @@
+package glox
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type OpCode byte
+
+const (
+	OP_CONSTANT      OpCode = iota // index:u16
+	OP_NIL                         //
+	OP_TRUE                        //
+	OP_FALSE                       //
+	OP_POP                         //
+	OP_DUP                         //
+	OP_DUP2                        //
+	OP_GET_LOCAL                   // slot:u8
+	OP_SET_LOCAL                   // slot:u8
+	OP_GET_GLOBAL                  // name:u16
+	OP_DEFINE_GLOBAL               // name:u16
+	OP_SET_GLOBAL                  // name:u16
+	OP_GET_UPVALUE                 // index:u8
+	OP_SET_UPVALUE                 // index:u8
+	OP_GET_PROPERTY                // name:u16
+	OP_SET_PROPERTY                // name:u16
+	OP_GET_SUPER                   // name:u16
+	OP_EQUAL                       //
+	OP_NOT_EQUAL                   //
+	OP_GREATER                     //
+	OP_GREATER_EQUAL               //
+	OP_LESS                        //
+	OP_LESS_EQUAL                  //
+	OP_ADD                         //
+	OP_SUBTRACT                    //
+	OP_MULTIPLY                    //
+	OP_DIVIDE                      //
+	OP_FLOOR_DIVIDE                //
+	OP_MODULO                      //
+	OP_POWER                       //
+	OP_BIT_AND                     //
+	OP_BIT_OR                      //
+	OP_BIT_XOR                     //
+	OP_SHIFT_LEFT                  //
+	OP_SHIFT_RIGHT                 //
+	OP_BIT_NOT                     //
+	OP_NOT                         //
+	OP_NEGATE                      //
+	OP_STRINGIFY                   //
+	OP_PRINT                       //
+	OP_JUMP                        // offset:u16
+	OP_JUMP_IF_FALSE               // offset:u16
+	OP_LOOP                        // offset:u16
+	OP_CALL                        // argc:u8
+	OP_CLOSURE                     // function:u16 (isLocal:u8 index:u8)*
+	OP_CLOSE_UPVALUE               //
+	OP_RETURN                      //
+	OP_CLASS                       // name:u16
+	OP_INHERIT                     //
+	OP_METHOD                      // name:u16
+	OP_LIST                        // count:u16
+	OP_MAP                         // count:u16
+	OP_GET_INDEX                   //
+	OP_SET_INDEX                   //
+	OP_ITER                        //
+	OP_FOR_ITER                    // exit:u16
+)
+
+var opNames = [...]string{
+	OP_CONSTANT:      "OP_CONSTANT",
+	OP_NIL:           "OP_NIL",
+	OP_TRUE:          "OP_TRUE",
+	OP_FALSE:         "OP_FALSE",
+	OP_POP:           "OP_POP",
+	OP_DUP:           "OP_DUP",
+	OP_DUP2:          "OP_DUP2",
+	OP_GET_LOCAL:     "OP_GET_LOCAL",
+	OP_SET_LOCAL:     "OP_SET_LOCAL",
+	OP_GET_GLOBAL:    "OP_GET_GLOBAL",
+	OP_DEFINE_GLOBAL: "OP_DEFINE_GLOBAL",
+	OP_SET_GLOBAL:    "OP_SET_GLOBAL",
+	OP_GET_UPVALUE:   "OP_GET_UPVALUE",
+	OP_SET_UPVALUE:   "OP_SET_UPVALUE",
+	OP_GET_PROPERTY:  "OP_GET_PROPERTY",
+	OP_SET_PROPERTY:  "OP_SET_PROPERTY",
+	OP_GET_SUPER:     "OP_GET_SUPER",
+	OP_EQUAL:         "OP_EQUAL",
+	OP_NOT_EQUAL:     "OP_NOT_EQUAL",
+	OP_GREATER:       "OP_GREATER",
+	OP_GREATER_EQUAL: "OP_GREATER_EQUAL",
+	OP_LESS:          "OP_LESS",
+	OP_LESS_EQUAL:    "OP_LESS_EQUAL",
+	OP_ADD:           "OP_ADD",
+	OP_SUBTRACT:      "OP_SUBTRACT",
+	OP_MULTIPLY:      "OP_MULTIPLY",
+	OP_DIVIDE:        "OP_DIVIDE",
+	OP_FLOOR_DIVIDE:  "OP_FLOOR_DIVIDE",
+	OP_MODULO:        "OP_MODULO",
+	OP_POWER:         "OP_POWER",
+	OP_BIT_AND:       "OP_BIT_AND",
+	OP_BIT_OR:        "OP_BIT_OR",
+	OP_BIT_XOR:       "OP_BIT_XOR",
+	OP_SHIFT_LEFT:    "OP_SHIFT_LEFT",
+	OP_SHIFT_RIGHT:   "OP_SHIFT_RIGHT",
+	OP_BIT_NOT:       "OP_BIT_NOT",
+	OP_NOT:           "OP_NOT",
+	OP_NEGATE:        "OP_NEGATE",
+	OP_STRINGIFY:     "OP_STRINGIFY",
+	OP_PRINT:         "OP_PRINT",
+	OP_JUMP:          "OP_JUMP",
+	OP_JUMP_IF_FALSE: "OP_JUMP_IF_FALSE",
+	OP_LOOP:          "OP_LOOP",
+	OP_CALL:          "OP_CALL",
+	OP_CLOSURE:       "OP_CLOSURE",
+	OP_CLOSE_UPVALUE: "OP_CLOSE_UPVALUE",
+	OP_RETURN:        "OP_RETURN",
+	OP_CLASS:         "OP_CLASS",
+	OP_INHERIT:       "OP_INHERIT",
+	OP_METHOD:        "OP_METHOD",
+	OP_LIST:          "OP_LIST",
+	OP_MAP:           "OP_MAP",
+	OP_ITER:          "OP_ITER",
+	OP_FOR_ITER:      "OP_FOR_ITER",
+	OP_GET_INDEX:     "OP_GET_INDEX",
+	OP_SET_INDEX:     "OP_SET_INDEX",
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return fmt.Sprintf("OP_UNKNOWN(%d)", byte(op))
+}
+
+// lineStart marks the first instruction offset that was compiled from a
+// token, the line table only grows when the token changes.
+type lineStart struct {
+	offset int
+	token  Token
+}
+
+type Chunk struct {
+	Code      []byte
+	Constants []Value
+	lines     []lineStart
+	// constants indexes Constants to reuse the slot of an equal constant
+	constants map[mapKey]int
+}
+
+func (c *Chunk) write(b byte, token Token) {
+	if len(c.lines) == 0 || c.lines[len(c.lines)-1].token.Span() != token.Span() {
+		c.lines = append(c.lines, lineStart{len(c.Code), token})
+	}
+	c.Code = append(c.Code, b)
+}
+
+func (c *Chunk) addConstant(value Value) int {
+	key, ok := constantKey(value)
+	if idx, found := c.constants[key]; ok && found {
+		return idx
+	}
+	c.Constants = append(c.Constants, value)
+	if ok {
+		if c.constants == nil {
+			c.constants = map[mapKey]int{}
+		}
+		c.constants[key] = len(c.Constants) - 1
+	}
+	return len(c.Constants) - 1
+}
+
+// constantKey returns the key that deduplicates a constant. Unlike hashKey
+// it keeps integers and floats, and 0 and -0, apart because a constant has
+// to keep its exact value.
+func constantKey(value Value) (mapKey, bool) {
+	if value.Type != OBJECT_VALUE {
+		return mapKey{value.Type, math.Float64bits(value.num), nil}, true
+	}
+	if !isComparable(value.obj) {
+		return mapKey{}, false
+	}
+	return mapKey{OBJECT_VALUE, 0, value.obj}, true
+}
+
+func (c *Chunk) readShort(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
+
+// Token returns the token the instruction at offset was compiled from.
+func (c *Chunk) Token(offset int) Token {
+	idx := sort.Search(len(c.lines), func(i int) bool {
+		return c.lines[i].offset > offset
+	})
+	if idx == 0 {
+		return Token{}
+	}
+	return c.lines[idx-1].token
+}
+
+// Line returns the source line of the instruction at offset.
+func (c *Chunk) Line(offset int) int {
+	return c.Token(offset).Line
+}
+
+// Disassemble returns a human readable listing of the chunk.
+func (c *Chunk) Disassemble(name string) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "== %s ==\n", name)
+	for offset := 0; offset < len(c.Code); {
+		offset = c.disassembleInstruction(b, offset)
+	}
+	return b.String()
+}
+
+func (c *Chunk) disassembleInstruction(b *strings.Builder, offset int) int {
+	fmt.Fprintf(b, "%04d %4d ", offset, c.Line(offset))
+	op := OpCode(c.Code[offset])
+	switch op {
+	case OP_CONSTANT, OP_GET_GLOBAL, OP_DEFINE_GLOBAL, OP_SET_GLOBAL, OP_GET_PROPERTY,
+		OP_SET_PROPERTY, OP_GET_SUPER, OP_CLASS, OP_METHOD:
+		idx := c.readShort(offset + 1)
+		fmt.Fprintf(b, "%-16s %4d '%v'\n", op, idx, c.Constants[idx])
+		return offset + 3
+	case OP_LIST, OP_MAP:
+		fmt.Fprintf(b, "%-16s %4d\n", op, c.readShort(offset+1))
+		return offset + 3
+	case OP_GET_LOCAL, OP_SET_LOCAL, OP_GET_UPVALUE, OP_SET_UPVALUE, OP_CALL:
+		fmt.Fprintf(b, "%-16s %4d\n", op, c.Code[offset+1])
+		return offset + 2
+	case OP_JUMP, OP_JUMP_IF_FALSE, OP_FOR_ITER:
+		fmt.Fprintf(b, "%-16s %4d -> %d\n", op, offset, offset+3+c.readShort(offset+1))
+		return offset + 3
+	case OP_LOOP:
+		fmt.Fprintf(b, "%-16s %4d -> %d\n", op, offset, offset+3-c.readShort(offset+1))
+		return offset + 3
+	case OP_CLOSURE:
+		idx := c.readShort(offset + 1)
+		function := c.Constants[idx].AsObject().(*vmFunction)
+		fmt.Fprintf(b, "%-16s %4d %v\n", op, idx, function)
+		offset += 3
+		for range function.upvalueCount {
+			kind := "upvalue"
+			if c.Code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(b, "%04d    |                     %s %d\n", offset, kind, c.Code[offset+1])
+			offset += 2
+		}
+		return offset
+	default:
+		fmt.Fprintf(b, "%v\n", op)
+		return offset + 1
+	}
+}
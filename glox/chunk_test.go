@@ -0,0 +1,33 @@
+package glox
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAddConstant(t *testing.T) {
+	c := &Chunk{}
+	list := ObjectValue(NewList())
+	values := []Value{
+		IntValue(1), NumberValue(1), NumberValue(0), NumberValue(math.Copysign(0, -1)),
+		ObjectValue("a"), BoolValue(true), NilValue, list,
+	}
+	indices := make([]int, len(values))
+	for idx, value := range values {
+		indices[idx] = c.addConstant(value)
+		if indices[idx] != idx {
+			t.Errorf("%v got slot %v, want a new slot %v", value, indices[idx], idx)
+		}
+	}
+	for idx, value := range values {
+		if got := c.addConstant(value); got != indices[idx] {
+			t.Errorf("adding %v again got slot %v, want %v", value, got, indices[idx])
+		}
+	}
+	if got := c.addConstant(ObjectValue(string([]byte("a")))); got != indices[4] {
+		t.Errorf("an equal string got slot %v, want %v", got, indices[4])
+	}
+	if got := c.addConstant(ObjectValue(NewList())); got != len(values) {
+		t.Errorf("a different list got slot %v, want a new slot", got)
+	}
+}
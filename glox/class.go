@@ -0,0 +1,63 @@
+package glox
+
+import "fmt"
+
+type LoxClass struct {
+	Name       string
+	superclass *LoxClass
+	methods    map[string]LoxFunction
+}
+
+func (c *LoxClass) findMethod(name string) (LoxFunction, bool) {
+	for class := c; class != nil; class = class.superclass {
+		if method, ok := class.methods[name]; ok {
+			return method, true
+		}
+	}
+	return LoxFunction{}, false
+}
+
+func (c *LoxClass) Arity() int {
+	if initializer, ok := c.findMethod("init"); ok {
+		return initializer.Arity()
+	}
+	return 0
+}
+
+func (c *LoxClass) Call(i *Interpreter, args []Value) (Value, error) {
+	instance := &LoxInstance{c, map[string]Value{}}
+	if initializer, ok := c.findMethod("init"); ok {
+		_, err := initializer.bind(instance).Call(i, args)
+		if err != nil {
+			return NilValue, err
+		}
+	}
+	return ObjectValue(instance), nil
+}
+
+func (c *LoxClass) String() string {
+	return "<class " + c.Name + ">"
+}
+
+type LoxInstance struct {
+	class  *LoxClass
+	fields map[string]Value
+}
+
+func (o *LoxInstance) Get(name Token) (Value, error) {
+	if value, ok := o.fields[name.Lexme]; ok {
+		return value, nil
+	}
+	if method, ok := o.class.findMethod(name.Lexme); ok {
+		return ObjectValue(method.bind(o)), nil
+	}
+	return NilValue, RunTimeError{name, fmt.Sprintf("Undefined property '%v'.", name.Lexme), nil}
+}
+
+func (o *LoxInstance) Set(name Token, value Value) {
+	o.fields[name.Lexme] = value
+}
+
+func (o *LoxInstance) String() string {
+	return "<" + o.class.Name + " instance>"
+}
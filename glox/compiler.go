@@ -0,0 +1,795 @@
+package glox
+
+const (
+	maxLocals   = 256
+	maxUpvalues = 256
+	maxConstant = 1<<16 - 1
+	maxJump     = 1<<16 - 1
+)
+
+type local struct {
+	name       string
+	depth      int
+	isCaptured bool
+}
+
+type upvalueRef struct {
+	index   byte
+	isLocal bool
+}
+
+// loop is the innermost loop being compiled. break and continue leave the
+// scopes opened inside the loop and jump forward to its exit or to the end
+// of the iteration.
+type loop struct {
+	enclosing  *loop
+	scopeDepth int
+	breaks     []int
+	continues  []int
+}
+
+type classCompiler struct {
+	enclosing     *classCompiler
+	hasSuperclass bool
+}
+
+// compiler turns the resolved ast of one function body into bytecode. Nested
+// functions get their own compiler that points back to the enclosing one so
+// that captured variables can be turned into upvalues.
+type compiler struct {
+	enclosing  *compiler
+	function   *vmFunction
+	kind       functionType
+	locals     []local
+	upvalues   []upvalueRef
+	scopeDepth int
+	class      *classCompiler
+	loop       *loop
+	token      Token
+	errors     *[]error
+}
+
+func Compile(stmts []Stmt) (*vmFunction, []error) {
+	errors := []error{}
+	c := newCompiler(nil, NONE_FUNCTION, "", &errors)
+	for idx, stmt := range stmts {
+		// the script returns the value of a trailing expression statement
+		if expr, ok := stmt.(*ExprStmt); ok && idx == len(stmts)-1 {
+			c.compileExpr(expr.Expr)
+			c.emitOp(OP_RETURN)
+			break
+		}
+		c.compileStmt(stmt)
+	}
+	function := c.end()
+	return function, errors
+}
+
+func newCompiler(enclosing *compiler, kind functionType, name string, errors *[]error) *compiler {
+	c := &compiler{
+		enclosing: enclosing,
+		function:  &vmFunction{name: name},
+		kind:      kind,
+		errors:    errors,
+	}
+	if enclosing != nil {
+		c.class = enclosing.class
+		c.token = enclosing.token
+	}
+	// slot zero holds the called closure, or the receiver inside methods
+	slotZero := ""
+	if kind == METHOD || kind == INITIALIZER {
+		slotZero = "this"
+	}
+	c.locals = append(c.locals, local{name: slotZero})
+	return c
+}
+
+// VisitBinaryExpr implements ExprVisitor.
+func (c *compiler) VisitBinaryExpr(expr *BinaryExpr) (Value, error) {
+	c.compileExpr(expr.Left)
+	c.compileExpr(expr.Right)
+	c.token = expr.Operator
+	c.emitBinaryOp(expr.Operator.Type)
+	return NilValue, nil
+}
+
+func (c *compiler) emitBinaryOp(operator TokenType) {
+	switch operator {
+	case EQUAL_EQUAL:
+		c.emitOp(OP_EQUAL)
+	case BANG_EQUAL:
+		c.emitOp(OP_NOT_EQUAL)
+	case GREATER:
+		c.emitOp(OP_GREATER)
+	case GREATER_EQUAL:
+		c.emitOp(OP_GREATER_EQUAL)
+	case LESS:
+		c.emitOp(OP_LESS)
+	case LESS_EQUAL:
+		c.emitOp(OP_LESS_EQUAL)
+	case PLUS:
+		c.emitOp(OP_ADD)
+	case MINUS:
+		c.emitOp(OP_SUBTRACT)
+	case STAR:
+		c.emitOp(OP_MULTIPLY)
+	case SLASH:
+		c.emitOp(OP_DIVIDE)
+	case TILDE_SLASH:
+		c.emitOp(OP_FLOOR_DIVIDE)
+	case PERCENT:
+		c.emitOp(OP_MODULO)
+	case STAR_STAR:
+		c.emitOp(OP_POWER)
+	case AMPERSAND:
+		c.emitOp(OP_BIT_AND)
+	case PIPE:
+		c.emitOp(OP_BIT_OR)
+	case CARET:
+		c.emitOp(OP_BIT_XOR)
+	case LESS_LESS:
+		c.emitOp(OP_SHIFT_LEFT)
+	case GREATER_GREATER:
+		c.emitOp(OP_SHIFT_RIGHT)
+	default:
+		panic("Unreachable")
+	}
+}
+
+// VisitGroupingExpr implements ExprVisitor.
+func (c *compiler) VisitGroupingExpr(expr *GroupingExpr) (Value, error) {
+	c.compileExpr(expr.Expr)
+	return NilValue, nil
+}
+
+// VisitLiteralExpr implements ExprVisitor.
+func (c *compiler) VisitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	switch value := expr.Value.(type) {
+	case nil:
+		c.emitOp(OP_NIL)
+	case bool:
+		if value {
+			c.emitOp(OP_TRUE)
+		} else {
+			c.emitOp(OP_FALSE)
+		}
+	case float64:
+		c.emitConstant(OP_CONSTANT, NumberValue(value))
+	case int64:
+		c.emitConstant(OP_CONSTANT, IntValue(value))
+	case string:
+		c.emitConstant(OP_CONSTANT, ObjectValue(value))
+	default:
+		panic("Unreachable")
+	}
+	return NilValue, nil
+}
+
+// VisitUnaryExpr implements ExprVisitor.
+func (c *compiler) VisitUnaryExpr(expr *UnaryExpr) (Value, error) {
+	c.compileExpr(expr.Expr)
+	c.token = expr.Operator
+	switch expr.Operator.Type {
+	case BANG:
+		c.emitOp(OP_NOT)
+	case MINUS:
+		c.emitOp(OP_NEGATE)
+	case TILDE:
+		c.emitOp(OP_BIT_NOT)
+	default:
+		panic("Unreachable")
+	}
+	return NilValue, nil
+}
+
+// VisitVariableExpr implements ExprVisitor.
+func (c *compiler) VisitVariableExpr(expr *VariableExpr) (Value, error) {
+	c.namedVariable(expr.Name, false)
+	return NilValue, nil
+}
+
+// VisitAssignExpr implements ExprVisitor.
+func (c *compiler) VisitAssignExpr(expr *AssignExpr) (Value, error) {
+	c.compileExpr(expr.Value)
+	c.namedVariable(expr.Name, true)
+	return NilValue, nil
+}
+
+// VisitLogicalExpr implements ExprVisitor.
+func (c *compiler) VisitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	c.compileExpr(expr.Left)
+	c.token = expr.Operator
+	if expr.Operator.Type == OR {
+		elseJump := c.emitJump(OP_JUMP_IF_FALSE)
+		endJump := c.emitJump(OP_JUMP)
+		c.patchJump(elseJump)
+		c.emitOp(OP_POP)
+		c.compileExpr(expr.Right)
+		c.patchJump(endJump)
+	} else {
+		endJump := c.emitJump(OP_JUMP_IF_FALSE)
+		c.emitOp(OP_POP)
+		c.compileExpr(expr.Right)
+		c.patchJump(endJump)
+	}
+	return NilValue, nil
+}
+
+// VisitCallExpr implements ExprVisitor.
+func (c *compiler) VisitCallExpr(expr *CallExpr) (Value, error) {
+	c.compileExpr(expr.Callee)
+	for _, arg := range expr.Arguments {
+		c.compileExpr(arg)
+	}
+	c.token = expr.Paren
+	c.emitOp(OP_CALL)
+	c.emitByte(byte(len(expr.Arguments)))
+	return NilValue, nil
+}
+
+// VisitGetExpr implements ExprVisitor.
+func (c *compiler) VisitGetExpr(expr *GetExpr) (Value, error) {
+	c.compileExpr(expr.Object)
+	c.token = expr.Name
+	c.emitConstant(OP_GET_PROPERTY, ObjectValue(expr.Name.Lexme))
+	return NilValue, nil
+}
+
+// VisitSetExpr implements ExprVisitor.
+func (c *compiler) VisitSetExpr(expr *SetExpr) (Value, error) {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Value)
+	c.token = expr.Name
+	c.emitConstant(OP_SET_PROPERTY, ObjectValue(expr.Name.Lexme))
+	return NilValue, nil
+}
+
+// VisitCompoundSetExpr implements ExprVisitor.
+func (c *compiler) VisitCompoundSetExpr(expr *CompoundSetExpr) (Value, error) {
+	c.compileExpr(expr.Object)
+	c.emitOp(OP_DUP)
+	c.token = expr.Name
+	c.emitConstant(OP_GET_PROPERTY, ObjectValue(expr.Name.Lexme))
+	c.compileExpr(expr.Value)
+	c.token = expr.Operator
+	c.emitBinaryOp(expr.Operator.Type)
+	c.token = expr.Name
+	c.emitConstant(OP_SET_PROPERTY, ObjectValue(expr.Name.Lexme))
+	return NilValue, nil
+}
+
+// VisitFunctionExpr implements ExprVisitor.
+func (c *compiler) VisitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	c.token = expr.Function.Name
+	c.compileFunction(expr.Function, FUNCTION)
+	return NilValue, nil
+}
+
+// VisitListExpr implements ExprVisitor.
+func (c *compiler) VisitListExpr(expr *ListExpr) (Value, error) {
+	for _, element := range expr.Elements {
+		c.compileExpr(element)
+	}
+	c.token = expr.Bracket
+	if len(expr.Elements) > maxConstant {
+		c.error("Too many elements in a list literal")
+	}
+	c.emitOp(OP_LIST)
+	c.emitShort(len(expr.Elements))
+	return NilValue, nil
+}
+
+// VisitMapExpr implements ExprVisitor.
+func (c *compiler) VisitMapExpr(expr *MapExpr) (Value, error) {
+	for idx := range expr.Keys {
+		c.compileExpr(expr.Keys[idx])
+		c.compileExpr(expr.Values[idx])
+	}
+	c.token = expr.Brace
+	if len(expr.Keys) > maxConstant {
+		c.error("Too many entries in a map literal")
+	}
+	c.emitOp(OP_MAP)
+	c.emitShort(len(expr.Keys))
+	return NilValue, nil
+}
+
+// VisitIndexExpr implements ExprVisitor.
+func (c *compiler) VisitIndexExpr(expr *IndexExpr) (Value, error) {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.token = expr.Bracket
+	c.emitOp(OP_GET_INDEX)
+	return NilValue, nil
+}
+
+// VisitIndexSetExpr implements ExprVisitor.
+func (c *compiler) VisitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.compileExpr(expr.Value)
+	c.token = expr.Bracket
+	c.emitOp(OP_SET_INDEX)
+	return NilValue, nil
+}
+
+// VisitCompoundIndexSetExpr implements ExprVisitor.
+func (c *compiler) VisitCompoundIndexSetExpr(expr *CompoundIndexSetExpr) (Value, error) {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.emitOp(OP_DUP2)
+	c.token = expr.Bracket
+	c.emitOp(OP_GET_INDEX)
+	c.compileExpr(expr.Value)
+	c.token = expr.Operator
+	c.emitBinaryOp(expr.Operator.Type)
+	c.token = expr.Bracket
+	c.emitOp(OP_SET_INDEX)
+	return NilValue, nil
+}
+
+// VisitThisExpr implements ExprVisitor.
+func (c *compiler) VisitThisExpr(expr *ThisExpr) (Value, error) {
+	c.namedVariable(expr.Keyword, false)
+	return NilValue, nil
+}
+
+// VisitSuperExpr implements ExprVisitor.
+func (c *compiler) VisitSuperExpr(expr *SuperExpr) (Value, error) {
+	this := expr.Keyword
+	this.Type, this.Lexme = THIS, "this"
+	c.namedVariable(this, false)
+	c.namedVariable(expr.Keyword, false)
+	c.emitConstant(OP_GET_SUPER, ObjectValue(expr.Method.Lexme))
+	return NilValue, nil
+}
+
+// VisitStringifyExpr implements ExprVisitor.
+func (c *compiler) VisitStringifyExpr(expr *StringifyExpr) (Value, error) {
+	c.compileExpr(expr.Expr)
+	c.token = expr.Part
+	c.emitOp(OP_STRINGIFY)
+	return NilValue, nil
+}
+
+// VisitExprStmt implements StmtVisitor.
+func (c *compiler) VisitExprStmt(expr *ExprStmt) (any, error) {
+	c.compileExpr(expr.Expr)
+	c.emitOp(OP_POP)
+	return nil, nil
+}
+
+// VisitPrintStmt implements StmtVisitor.
+func (c *compiler) VisitPrintStmt(expr *PrintStmt) (any, error) {
+	c.compileExpr(expr.Expr)
+	c.token = expr.Keyword
+	c.emitOp(OP_PRINT)
+	return nil, nil
+}
+
+// VisitVarDecl implements StmtVisitor.
+func (c *compiler) VisitVarDecl(expr *VarDecl) (any, error) {
+	c.token = expr.Name
+	if expr.Initializer != nil {
+		c.compileExpr(expr.Initializer)
+	} else {
+		c.emitOp(OP_NIL)
+	}
+	c.defineVariable(expr.Name)
+	return nil, nil
+}
+
+// VisitBlock implements StmtVisitor.
+func (c *compiler) VisitBlock(expr *Block) (any, error) {
+	c.beginScope()
+	for _, stmt := range expr.Stmts {
+		c.compileStmt(stmt)
+	}
+	c.endScope()
+	return nil, nil
+}
+
+// VisitIfStmt implements StmtVisitor.
+func (c *compiler) VisitIfStmt(expr *IfStmt) (any, error) {
+	c.compileExpr(expr.Condition)
+	thenJump := c.emitJump(OP_JUMP_IF_FALSE)
+	c.emitOp(OP_POP)
+	c.compileStmt(expr.ThenBranch)
+	elseJump := c.emitJump(OP_JUMP)
+	c.patchJump(thenJump)
+	c.emitOp(OP_POP)
+	c.compileStmt(expr.ElseBranch)
+	c.patchJump(elseJump)
+	return nil, nil
+}
+
+// VisitWhileStmt implements StmtVisitor.
+func (c *compiler) VisitWhileStmt(expr *WhileStmt) (any, error) {
+	c.compileLoop(expr.Condition, nil, expr.Body)
+	return nil, nil
+}
+
+// VisitForStmt implements StmtVisitor.
+func (c *compiler) VisitForStmt(expr *ForStmt) (any, error) {
+	c.beginScope()
+	c.compileStmt(expr.Initializer)
+	c.compileLoop(expr.Condition, expr.Increment, expr.Body)
+	c.endScope()
+	return nil, nil
+}
+
+// compileLoop compiles a loop that checks condition before every iteration
+// and evaluates increment after it, both of which may be nil.
+func (c *compiler) compileLoop(condition, increment Expr, body Stmt) {
+	loopStart := len(c.chunk().Code)
+	exitJump := -1
+	if condition != nil {
+		c.compileExpr(condition)
+		exitJump = c.emitJump(OP_JUMP_IF_FALSE)
+		c.emitOp(OP_POP)
+	}
+
+	c.loop = &loop{enclosing: c.loop, scopeDepth: c.scopeDepth}
+	c.compileStmt(body)
+	loop := c.loop
+	c.loop = loop.enclosing
+
+	for _, jump := range loop.continues {
+		c.patchJump(jump)
+	}
+	if increment != nil {
+		c.compileExpr(increment)
+		c.emitOp(OP_POP)
+	}
+	c.emitLoop(loopStart)
+	if exitJump != -1 {
+		c.patchJump(exitJump)
+		c.emitOp(OP_POP)
+	}
+	for _, jump := range loop.breaks {
+		c.patchJump(jump)
+	}
+}
+
+// VisitForInStmt implements StmtVisitor.
+func (c *compiler) VisitForInStmt(expr *ForInStmt) (any, error) {
+	// the iterator lives in a hidden local below the loop variable
+	c.beginScope()
+	c.compileExpr(expr.Iterable)
+	c.token = expr.In
+	c.emitOp(OP_ITER)
+	c.addLocal(Token{Lexme: "for iterator"})
+	c.markInitialized()
+
+	loopStart := len(c.chunk().Code)
+	exitJump := c.emitJump(OP_FOR_ITER)
+	c.loop = &loop{enclosing: c.loop, scopeDepth: c.scopeDepth}
+	c.beginScope()
+	c.addLocal(expr.Name)
+	c.markInitialized()
+	c.compileStmt(expr.Body)
+	c.endScope()
+	loop := c.loop
+	c.loop = loop.enclosing
+
+	for _, jump := range loop.continues {
+		c.patchJump(jump)
+	}
+	c.emitLoop(loopStart)
+	c.patchJump(exitJump)
+	for _, jump := range loop.breaks {
+		c.patchJump(jump)
+	}
+	c.endScope()
+	return nil, nil
+}
+
+// VisitBreakStmt implements StmtVisitor.
+func (c *compiler) VisitBreakStmt(expr *BreakStmt) (any, error) {
+	c.token = expr.Keyword
+	c.discardLocals(c.loop.scopeDepth)
+	c.loop.breaks = append(c.loop.breaks, c.emitJump(OP_JUMP))
+	return nil, nil
+}
+
+// VisitContinueStmt implements StmtVisitor.
+func (c *compiler) VisitContinueStmt(expr *ContinueStmt) (any, error) {
+	c.token = expr.Keyword
+	c.discardLocals(c.loop.scopeDepth)
+	c.loop.continues = append(c.loop.continues, c.emitJump(OP_JUMP))
+	return nil, nil
+}
+
+// VisitFunction implements StmtVisitor.
+func (c *compiler) VisitFunction(expr *Function) (any, error) {
+	c.token = expr.Name
+	if c.scopeDepth > 0 {
+		// declare the local before compiling the body so the function can
+		// refer to itself recursively
+		c.addLocal(expr.Name)
+		c.markInitialized()
+		c.compileFunction(expr, FUNCTION)
+		return nil, nil
+	}
+	c.compileFunction(expr, FUNCTION)
+	c.defineVariable(expr.Name)
+	return nil, nil
+}
+
+// VisitReturnStmt implements StmtVisitor.
+func (c *compiler) VisitReturnStmt(expr *ReturnStmt) (any, error) {
+	c.token = expr.Keyword
+	if expr.Value == nil {
+		c.emitReturn()
+		return nil, nil
+	}
+	c.compileExpr(expr.Value)
+	c.emitOp(OP_RETURN)
+	return nil, nil
+}
+
+// VisitClassStmt implements StmtVisitor.
+func (c *compiler) VisitClassStmt(expr *ClassStmt) (any, error) {
+	c.token = expr.Name
+	if c.scopeDepth > 0 {
+		c.addLocal(expr.Name)
+		c.markInitialized()
+		c.emitConstant(OP_CLASS, ObjectValue(expr.Name.Lexme))
+	} else {
+		c.emitConstant(OP_CLASS, ObjectValue(expr.Name.Lexme))
+		c.defineVariable(expr.Name)
+	}
+
+	c.class = &classCompiler{enclosing: c.class}
+	if expr.Superclass != nil {
+		c.namedVariable(expr.Superclass.Name, false)
+		c.beginScope()
+		super := expr.Superclass.Name
+		super.Type, super.Lexme = SUPER, "super"
+		c.addLocal(super)
+		c.markInitialized()
+		c.namedVariable(expr.Name, false)
+		c.token = expr.Superclass.Name
+		c.emitOp(OP_INHERIT)
+		c.class.hasSuperclass = true
+	}
+
+	c.namedVariable(expr.Name, false)
+	for _, method := range expr.Methods {
+		kind := METHOD
+		if method.Name.Lexme == "init" {
+			kind = INITIALIZER
+		}
+		c.compileFunction(method, kind)
+		c.emitConstant(OP_METHOD, ObjectValue(method.Name.Lexme))
+	}
+	c.emitOp(OP_POP)
+
+	if c.class.hasSuperclass {
+		c.endScope()
+	}
+	c.class = c.class.enclosing
+	return nil, nil
+}
+
+func (c *compiler) compileFunction(declaration *Function, kind functionType) {
+	fc := newCompiler(c, kind, declaration.Name.Lexme, c.errors)
+	fc.function.arity = len(declaration.Params)
+	fc.beginScope()
+	for _, param := range declaration.Params {
+		fc.addLocal(param)
+		fc.markInitialized()
+	}
+	for _, stmt := range declaration.Body {
+		fc.compileStmt(stmt)
+	}
+	function := fc.end()
+
+	c.emitConstant(OP_CLOSURE, ObjectValue(function))
+	for _, upvalue := range fc.upvalues {
+		if upvalue.isLocal {
+			c.emitByte(1)
+		} else {
+			c.emitByte(0)
+		}
+		c.emitByte(upvalue.index)
+	}
+}
+
+func (c *compiler) end() *vmFunction {
+	c.emitReturn()
+	c.function.upvalueCount = len(c.upvalues)
+	return c.function
+}
+
+func (c *compiler) namedVariable(name Token, assign bool) {
+	c.token = name
+	getOp, setOp := OP_GET_GLOBAL, OP_SET_GLOBAL
+	arg := c.resolveLocal(name)
+	if arg != -1 {
+		getOp, setOp = OP_GET_LOCAL, OP_SET_LOCAL
+	} else if arg = c.resolveUpvalue(name); arg != -1 {
+		getOp, setOp = OP_GET_UPVALUE, OP_SET_UPVALUE
+	} else {
+		op := getOp
+		if assign {
+			op = setOp
+		}
+		c.emitConstant(op, ObjectValue(name.Lexme))
+		return
+	}
+
+	if assign {
+		c.emitOp(setOp)
+	} else {
+		c.emitOp(getOp)
+	}
+	c.emitByte(byte(arg))
+}
+
+func (c *compiler) resolveLocal(name Token) int {
+	for idx := len(c.locals) - 1; idx >= 0; idx-- {
+		if c.locals[idx].name == name.Lexme {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (c *compiler) resolveUpvalue(name Token) int {
+	if c.enclosing == nil {
+		return -1
+	}
+	if local := c.enclosing.resolveLocal(name); local != -1 {
+		c.enclosing.locals[local].isCaptured = true
+		return c.addUpvalue(byte(local), true)
+	}
+	if upvalue := c.enclosing.resolveUpvalue(name); upvalue != -1 {
+		return c.addUpvalue(byte(upvalue), false)
+	}
+	return -1
+}
+
+func (c *compiler) addUpvalue(index byte, isLocal bool) int {
+	for idx, upvalue := range c.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return idx
+		}
+	}
+	if len(c.upvalues) == maxUpvalues {
+		c.error("Too many closure variables in function")
+		return 0
+	}
+	c.upvalues = append(c.upvalues, upvalueRef{index, isLocal})
+	return len(c.upvalues) - 1
+}
+
+func (c *compiler) defineVariable(name Token) {
+	if c.scopeDepth > 0 {
+		c.addLocal(name)
+		c.markInitialized()
+		return
+	}
+	c.emitConstant(OP_DEFINE_GLOBAL, ObjectValue(name.Lexme))
+}
+
+func (c *compiler) addLocal(name Token) {
+	if len(c.locals) == maxLocals {
+		c.error("Too many local variables in function")
+		return
+	}
+	c.locals = append(c.locals, local{name: name.Lexme, depth: -1})
+}
+
+func (c *compiler) markInitialized() {
+	if len(c.locals) != 0 {
+		c.locals[len(c.locals)-1].depth = c.scopeDepth
+	}
+}
+
+func (c *compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *compiler) endScope() {
+	c.scopeDepth--
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		if c.locals[len(c.locals)-1].isCaptured {
+			c.emitOp(OP_CLOSE_UPVALUE)
+		} else {
+			c.emitOp(OP_POP)
+		}
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+// discardLocals pops the locals deeper than depth off the stack without
+// ending their scopes, for jumps out of them.
+func (c *compiler) discardLocals(depth int) {
+	for idx := len(c.locals) - 1; idx >= 0 && c.locals[idx].depth > depth; idx-- {
+		if c.locals[idx].isCaptured {
+			c.emitOp(OP_CLOSE_UPVALUE)
+		} else {
+			c.emitOp(OP_POP)
+		}
+	}
+}
+
+func (c *compiler) compileStmt(stmt Stmt) {
+	if stmt == nil {
+		return
+	}
+	stmt.Accept(c)
+}
+
+func (c *compiler) compileExpr(expr Expr) {
+	expr.Accept(c)
+}
+
+func (c *compiler) chunk() *Chunk {
+	return &c.function.chunk
+}
+
+func (c *compiler) emitByte(b byte) {
+	c.chunk().write(b, c.token)
+}
+
+func (c *compiler) emitOp(op OpCode) {
+	c.emitByte(byte(op))
+}
+
+func (c *compiler) emitShort(v int) {
+	c.emitByte(byte(v >> 8))
+	c.emitByte(byte(v))
+}
+
+func (c *compiler) emitConstant(op OpCode, value Value) {
+	idx := c.chunk().addConstant(value)
+	if idx > maxConstant {
+		c.error("Too many constants in one chunk")
+		return
+	}
+	c.emitOp(op)
+	c.emitShort(idx)
+}
+
+func (c *compiler) emitReturn() {
+	if c.kind == INITIALIZER {
+		c.emitOp(OP_GET_LOCAL)
+		c.emitByte(0)
+	} else {
+		c.emitOp(OP_NIL)
+	}
+	c.emitOp(OP_RETURN)
+}
+
+func (c *compiler) emitJump(op OpCode) int {
+	c.emitOp(op)
+	c.emitShort(0xffff)
+	return len(c.chunk().Code) - 2
+}
+
+func (c *compiler) patchJump(offset int) {
+	jump := len(c.chunk().Code) - offset - 2
+	if jump > maxJump {
+		c.error("Too much code to jump over")
+		return
+	}
+	c.chunk().Code[offset] = byte(jump >> 8)
+	c.chunk().Code[offset+1] = byte(jump)
+}
+
+func (c *compiler) emitLoop(loopStart int) {
+	c.emitOp(OP_LOOP)
+	offset := len(c.chunk().Code) - loopStart + 2
+	if offset > maxJump {
+		c.error("Loop body too large")
+		return
+	}
+	c.emitShort(offset)
+}
+
+func (c *compiler) error(message string) {
+	*c.errors = append(*c.errors, ParseError{c.token, message, "compile"})
+}
@@ -0,0 +1,244 @@
+package glox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Note adds context to a diagnostic, its span is zero when it does not point
+// at any source.
+type Note struct {
+	Message string `json:"message"`
+	Span    Span   `json:"span"`
+}
+
+// StackFrame is one entry of a lox traceback, Span is where Function was
+// executing when the error was raised.
+type StackFrame struct {
+	Function string `json:"function"`
+	Span     Span   `json:"span"`
+}
+
+func (f StackFrame) String() string {
+	if f.Span.File == "" {
+		return fmt.Sprintf("at %v (line %v)", f.Function, f.Span.Line)
+	}
+	return fmt.Sprintf("at %v (%v:%v)", f.Function, f.Span.File, f.Span.Line)
+}
+
+// maxTraceLines bounds how many frames of a traceback are printed, deep
+// recursion keeps its innermost and outermost frames.
+const maxTraceLines = 20
+
+func formatTrace(trace []StackFrame) []string {
+	lines := make([]string, 0, min(len(trace), maxTraceLines+1))
+	for idx, frame := range trace {
+		if len(trace) > maxTraceLines && idx == maxTraceLines/2 {
+			lines = append(lines, fmt.Sprintf("... %v more frames", len(trace)-maxTraceLines))
+		}
+		if len(trace) > maxTraceLines && idx >= maxTraceLines/2 && idx < len(trace)-maxTraceLines/2 {
+			continue
+		}
+		lines = append(lines, frame.String())
+	}
+	return lines
+}
+
+// Diagnostic is a structured report about a program. Code names the pass
+// that produced it, one of lexical, syntax, resolve, compile or runtime.
+// Runtime errors raised inside a function carry the lox call stack in
+// Trace.
+type Diagnostic struct {
+	Severity Severity     `json:"severity"`
+	Code     string       `json:"code,omitempty"`
+	Message  string       `json:"message"`
+	Span     Span         `json:"span"`
+	Notes    []Note       `json:"notes,omitempty"`
+	Trace    []StackFrame `json:"trace,omitempty"`
+}
+
+func (d Diagnostic) Error() string {
+	header := d.Severity.String()
+	if d.Code != "" {
+		header += "[" + d.Code + "]"
+	}
+	if d.Span.IsZero() {
+		return header + ": " + d.Message
+	}
+	return fmt.Sprintf("%v: %v: %v", d.Span, header, d.Message)
+}
+
+func (e ParseError) Diagnostic() Diagnostic {
+	code := e.Code
+	if code == "" {
+		code = "syntax"
+	}
+	return Diagnostic{Severity: SeverityError, Code: code, Message: e.Message, Span: e.Token.Span()}
+}
+
+func (e RunTimeError) Diagnostic() Diagnostic {
+	return Diagnostic{Severity: SeverityError, Code: "runtime", Message: e.m, Span: e.t.Span(), Trace: e.trace}
+}
+
+// Diagnostics returns the diagnostics describing err, a *SyntaxError yields
+// one per static error. Errors that do not come from lox are reported
+// without a code or span.
+func Diagnostics(err error) []Diagnostic {
+	if err == nil {
+		return nil
+	}
+	var syntax *SyntaxError
+	if errors.As(err, &syntax) {
+		var diagnostics []Diagnostic
+		for _, err := range syntax.Errors {
+			diagnostics = append(diagnostics, Diagnostics(err)...)
+		}
+		return diagnostics
+	}
+	var diagnostic interface{ Diagnostic() Diagnostic }
+	if errors.As(err, &diagnostic) {
+		return []Diagnostic{diagnostic.Diagnostic()}
+	}
+	return []Diagnostic{{Severity: SeverityError, Message: err.Error()}}
+}
+
+type DiagnosticFormat int
+
+const (
+	TextDiagnostics DiagnosticFormat = iota
+	JSONDiagnostics
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[1;31m"
+	ansiBlue  = "\x1b[1;34m"
+)
+
+// DiagnosticRenderer writes diagnostics either as text with the offending
+// source line underlined, or as one json object per line.
+type DiagnosticRenderer struct {
+	Format DiagnosticFormat
+	Color  bool
+}
+
+// Render writes diagnostics to w, source is the code their spans point
+// into and may be empty when it is not available.
+func (r DiagnosticRenderer) Render(w io.Writer, source string, diagnostics ...Diagnostic) error {
+	return r.render(w, func(string) (string, bool) { return source, true }, diagnostics...)
+}
+
+// render is like Render for diagnostics from several inputs, sources returns
+// the code of the input with the given file name. Diagnostics in unknown
+// inputs are rendered without a snippet.
+func (r DiagnosticRenderer) render(w io.Writer, sources func(file string) (string, bool), diagnostics ...Diagnostic) error {
+	if r.Format == JSONDiagnostics {
+		encoder := json.NewEncoder(w)
+		for _, diagnostic := range diagnostics {
+			if err := encoder.Encode(diagnostic); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	b := &strings.Builder{}
+	for _, diagnostic := range diagnostics {
+		source, ok := sources(diagnostic.Span.File)
+		r.renderText(b, source, ok, diagnostic)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r DiagnosticRenderer) paint(style, text string) string {
+	if !r.Color {
+		return text
+	}
+	return style + text + ansiReset
+}
+
+func (r DiagnosticRenderer) renderText(b *strings.Builder, source string, quote bool, d Diagnostic) {
+	header := d.Severity.String()
+	if d.Code != "" {
+		header += "[" + d.Code + "]"
+	}
+	fmt.Fprintf(b, "%s%s\n", r.paint(ansiRed, header), r.paint(ansiBold, ": "+d.Message))
+
+	gutter := strings.Repeat(" ", len(strconv.Itoa(d.Span.Line)))
+	if !d.Span.IsZero() {
+		fmt.Fprintf(b, "%s%s %v\n", gutter, r.paint(ansiBlue, "-->"), d.Span)
+		if line, column, width, ok := sourceLine(source, d.Span); quote && ok {
+			bar := r.paint(ansiBlue, "|")
+			fmt.Fprintf(b, "%s %s\n", gutter, bar)
+			fmt.Fprintf(b, "%s %s %s\n", r.paint(ansiBlue, strconv.Itoa(d.Span.Line)), bar, line)
+			fmt.Fprintf(b, "%s %s %s%s\n", gutter, bar, column, r.paint(ansiRed, strings.Repeat("^", width)))
+		}
+	}
+	for _, note := range d.Notes {
+		fmt.Fprintf(b, "%s %s %s", gutter, r.paint(ansiBlue, "="), r.paint(ansiBold, "note: ")+note.Message)
+		if !note.Span.IsZero() {
+			fmt.Fprintf(b, " (%v)", note.Span)
+		}
+		b.WriteString("\n")
+	}
+	for _, line := range formatTrace(d.Trace) {
+		fmt.Fprintf(b, "%s %s\n", gutter, line)
+	}
+}
+
+// sourceLine returns the line of source the span starts on, the padding
+// that lines up with the start of the span and the width of the underline.
+// Spans running past the end of the line are underlined up to its end.
+func sourceLine(source string, span Span) (line, padding string, width int, ok bool) {
+	if span.Start < 0 || span.Start > len(source) || span.End < span.Start {
+		return "", "", 0, false
+	}
+	lineStart := strings.LastIndexByte(source[:span.Start], '\n') + 1
+	lineEnd := strings.IndexByte(source[span.Start:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(source)
+	} else {
+		lineEnd += span.Start
+	}
+	end := min(span.End, lineEnd)
+
+	line = strings.TrimRight(source[lineStart:lineEnd], "\r")
+	padding = strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, source[lineStart:span.Start])
+	width = max(utf8.RuneCountInString(source[span.Start:end]), 1)
+	return line, padding, width, true
+}
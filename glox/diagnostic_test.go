@@ -0,0 +1,130 @@
+package glox
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRenderText(t *testing.T) {
+	src := "var x = 1;\nprint x +;\n"
+	_, err := New().Eval(src)
+	diagnostics := Diagnostics(err)
+	if len(diagnostics) != 1 {
+		t.Fatalf("got %v diagnostics, want 1: %v", len(diagnostics), err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := (DiagnosticRenderer{}).Render(out, src, diagnostics...); err != nil {
+		t.Fatal(err)
+	}
+	want := `error[syntax]: Expected Expression
+ --> 2:10
+  |
+2 | print x +;
+  |          ^
+`
+	if out.String() != want {
+		t.Errorf("rendered\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestRenderTextWithoutSource(t *testing.T) {
+	out := &bytes.Buffer{}
+	diagnostic := Diagnostic{
+		Severity: SeverityWarning,
+		Message:  "something odd",
+		Span:     Span{Line: 3, Column: 1, Start: 40, End: 41},
+		Notes:    []Note{{Message: "noticed here"}},
+	}
+	if err := (DiagnosticRenderer{}).Render(out, "", diagnostic); err != nil {
+		t.Fatal(err)
+	}
+	want := "warning: something odd\n --> 3:1\n  = note: noticed here\n"
+	if out.String() != want {
+		t.Errorf("rendered %q, want %q", out, want)
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	src := "print -nil;"
+	_, err := New(WithStdout(&bytes.Buffer{})).Eval(src)
+	out := &bytes.Buffer{}
+	if err := (DiagnosticRenderer{Color: true}).Render(out, src, Diagnostics(err)...); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{ansiRed + "error[runtime]" + ansiReset, ansiBlue + "|" + ansiReset} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("rendered %q, want it to contain %q", out, want)
+		}
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	src := "var a = 1;\nvar a = 2;\n{ var b = b; }"
+	_, err := New().Eval(src)
+	out := &bytes.Buffer{}
+	if err := (DiagnosticRenderer{Format: JSONDiagnostics}).Render(out, src, Diagnostics(err)...); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) == 0 {
+		t.Fatal("no diagnostics rendered")
+	}
+	for _, line := range lines {
+		var diagnostic struct {
+			Severity string
+			Code     string
+			Message  string
+			Span     Span
+		}
+		if err := json.Unmarshal([]byte(line), &diagnostic); err != nil {
+			t.Fatalf("%q is not json: %v", line, err)
+		}
+		if diagnostic.Severity != "error" || diagnostic.Code != "resolve" || diagnostic.Message == "" || diagnostic.Span.Line == 0 {
+			t.Errorf("unexpected diagnostic %+v", diagnostic)
+		}
+	}
+}
+
+func TestRunReportsDiagnostics(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	vm := New(WithStdout(&bytes.Buffer{}), WithStderr(stderr), WithDiagnosticFormat(JSONDiagnostics))
+	if err := vm.Run("print undefined;"); err == nil {
+		t.Fatal("Run succeeded, want an error")
+	}
+	var diagnostic struct {
+		Code string
+		Span Span
+	}
+	if err := json.Unmarshal(stderr.Bytes(), &diagnostic); err != nil {
+		t.Fatalf("stderr %q is not a json diagnostic: %v", stderr, err)
+	}
+	if diagnostic.Code != "runtime" || diagnostic.Span.Column != 7 {
+		t.Errorf("unexpected diagnostic %+v", diagnostic)
+	}
+}
+
+func TestReplQuotesTheFailingInput(t *testing.T) {
+	for _, b := range backends {
+		stderr := &bytes.Buffer{}
+		in := strings.NewReader("fun f(x) { return -x; }\nprint f(1);\nprint f(\"s\");\n")
+		vm := New(WithBackend(b.backend), WithStdout(&bytes.Buffer{}), WithStderr(stderr), WithStdin(in))
+		if err := vm.Repl(); err != nil {
+			t.Fatal(err)
+		}
+		want := `error[runtime]: negation can only be done on numbers
+ --> <repl:1>:1:19
+  |
+1 | fun f(x) { return -x; }
+  |                   ^
+  at f (<repl:1>:1)
+  at script (<repl:3>:1)
+`
+		if stderr.String() != want {
+			t.Errorf("%v: stderr is\n%s\nwant\n%s", b.name, stderr, want)
+		}
+	}
+}
@@ -4,10 +4,10 @@ import "fmt"
 
 type Enviorment struct {
 	enclosing *Enviorment
-	values    map[string]any
+	values    map[string]Value
 }
 
-func (e *Enviorment) Get(name Token) (any, error) {
+func (e *Enviorment) Get(name Token) (Value, error) {
 	v, ok := e.values[name.Lexme]
 	if ok {
 		return v, nil
@@ -15,14 +15,14 @@ func (e *Enviorment) Get(name Token) (any, error) {
 	if e.enclosing != nil {
 		return e.enclosing.Get(name)
 	}
-	return nil, RunTimeError{t: name, m: fmt.Sprintf("Undefined Variable '%v'.", name.Lexme)}
+	return NilValue, RunTimeError{t: name, m: fmt.Sprintf("Undefined Variable '%v'.", name.Lexme)}
 }
 
-func (e *Enviorment) Put(name string, value any) {
+func (e *Enviorment) Put(name string, value Value) {
 	e.values[name] = value
 }
 
-func (e *Enviorment) Assign(name Token, value any) error {
+func (e *Enviorment) Assign(name Token, value Value) error {
 	_, exists := e.values[name.Lexme]
 	if exists {
 		e.Put(name.Lexme, value)
@@ -31,5 +31,21 @@ func (e *Enviorment) Assign(name Token, value any) error {
 	if e.enclosing != nil {
 		return e.enclosing.Assign(name, value)
 	}
-	return fmt.Errorf("undefined variable '%v'", name.Lexme)
+	return RunTimeError{t: name, m: fmt.Sprintf("Undefined Variable '%v'.", name.Lexme)}
+}
+
+func (e *Enviorment) ancestor(distance int) *Enviorment {
+	env := e
+	for range distance {
+		env = env.enclosing
+	}
+	return env
+}
+
+func (e *Enviorment) GetAt(distance int, name string) Value {
+	return e.ancestor(distance).values[name]
+}
+
+func (e *Enviorment) AssignAt(distance int, name Token, value Value) {
+	e.ancestor(distance).Put(name.Lexme, value)
 }
@@ -0,0 +1,50 @@
+package glox
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestGolden runs every program in testdata on both backends and compares
+// everything it writes, output and diagnostics, with its golden file. Run
+// with -update to rewrite the golden files from the tree-walker.
+func TestGolden(t *testing.T) {
+	programs, err := filepath.Glob("testdata/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backends := []struct {
+		name    string
+		backend Backend
+	}{{"tree", TreeWalker}, {"vm", Bytecode}}
+
+	for _, program := range programs {
+		golden := strings.TrimSuffix(program, ".lox") + ".golden"
+		for _, b := range backends {
+			t.Run(filepath.Base(program)+"/"+b.name, func(t *testing.T) {
+				out := &bytes.Buffer{}
+				New(WithBackend(b.backend), WithStdout(out), WithStderr(out)).RunFile(program)
+
+				if *update && b.backend == TreeWalker {
+					if err := os.WriteFile(golden, out.Bytes(), 0o644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := out.String(); got != string(want) {
+					t.Errorf("output differs from %v\ngot:\n%s\nwant:\n%s", golden, got, want)
+				}
+			})
+		}
+	}
+}
@@ -1,38 +1,319 @@
 package glox
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
 )
 
 type RunTimeError struct {
-	t Token
-	m string
+	t     Token
+	m     string
+	trace []StackFrame
 }
 
 func (e RunTimeError) Error() string {
-	return fmt.Sprintf("Error :%v around '%v': %v", e.t.Line, e.t.Lexme, e.m)
+	var message string
+	if e.t.Lexme == "" {
+		message = fmt.Sprintf("Error :%v: %v", e.t.Line, e.m)
+	} else {
+		message = fmt.Sprintf("Error :%v around '%v': %v", e.t.Line, e.t.Lexme, e.m)
+	}
+	for _, line := range formatTrace(e.trace) {
+		message += "\n  " + line
+	}
+	return message
+}
+
+// Trace returns the lox call stack at the time of the error, innermost
+// call first. It is empty for errors raised outside of any function.
+func (e RunTimeError) Trace() []StackFrame {
+	return e.trace
+}
+
+// callSite is an active call of a lox function in the interpreter.
+type callSite struct {
+	function string
+	paren    Token
 }
 
 type Interpreter struct {
 	*Enviorment
+	globals *Enviorment
+	locals  map[Expr]int
+	frames  []callSite
+	stdout  io.Writer
+}
+
+func newInterpreter() *Interpreter {
+	globals := &Enviorment{
+		values:    map[string]Value{},
+		enclosing: nil,
+	}
+	globals.Put("clock", ObjectValue(clock))
+	globals.Put("range", ObjectValue(rangeFunction))
+	return &Interpreter{
+		Enviorment: globals,
+		globals:    globals,
+		locals:     map[Expr]int{},
+		stdout:     os.Stdout,
+	}
+}
+
+func (i *Interpreter) resolve(expr Expr, depth int) {
+	i.locals[expr] = depth
+}
+
+func (i *Interpreter) lookUpVariable(name Token, expr Expr) (Value, error) {
+	distance, ok := i.locals[expr]
+	if ok {
+		return i.GetAt(distance, name.Lexme), nil
+	}
+	return i.globals.Get(name)
 }
 
 // VisitFunction implements StmtVisitor.
-func (i *Interpreter) VisitFunction(expr Function) (any, error) {
-	i.Enviorment.Put(expr.Name.Lexme, expr)
+func (i *Interpreter) VisitFunction(expr *Function) (any, error) {
+	i.Enviorment.Put(expr.Name.Lexme, ObjectValue(LoxFunction{expr, i.Enviorment, false}))
 	return nil, nil
 }
 
+// VisitClassStmt implements StmtVisitor.
+func (i *Interpreter) VisitClassStmt(expr *ClassStmt) (any, error) {
+	var superclass *LoxClass
+	if expr.Superclass != nil {
+		value, err := i.evaluate(expr.Superclass)
+		if err != nil {
+			return nil, err
+		}
+		class, ok := value.AsObject().(*LoxClass)
+		if !ok {
+			return nil, RunTimeError{expr.Superclass.Name, "Superclass must be a class", nil}
+		}
+		superclass = class
+	}
+
+	i.Enviorment.Put(expr.Name.Lexme, NilValue)
+
+	env := i.Enviorment
+	if superclass != nil {
+		env = &Enviorment{i.Enviorment, map[string]Value{}}
+		env.Put("super", ObjectValue(superclass))
+	}
+
+	methods := map[string]LoxFunction{}
+	for _, method := range expr.Methods {
+		methods[method.Name.Lexme] = LoxFunction{method, env, method.Name.Lexme == "init"}
+	}
+	class := &LoxClass{expr.Name.Lexme, superclass, methods}
+	i.Enviorment.Put(expr.Name.Lexme, ObjectValue(class))
+	return nil, nil
+}
+
+// VisitSuperExpr implements ExprVisitor.
+func (i *Interpreter) VisitSuperExpr(expr *SuperExpr) (Value, error) {
+	distance := i.locals[expr]
+	superclass := i.GetAt(distance, "super").AsObject().(*LoxClass)
+	instance := i.GetAt(distance-1, "this").AsObject().(*LoxInstance)
+
+	method, ok := superclass.findMethod(expr.Method.Lexme)
+	if !ok {
+		return NilValue, RunTimeError{expr.Method, fmt.Sprintf("Undefined property '%v'.", expr.Method.Lexme), nil}
+	}
+	return ObjectValue(method.bind(instance)), nil
+}
+
+// VisitGetExpr implements ExprVisitor.
+func (i *Interpreter) VisitGetExpr(expr *GetExpr) (Value, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return NilValue, err
+	}
+	instance, ok := object.AsObject().(*LoxInstance)
+	if !ok {
+		method, err := getMethod(object, expr.Name.Lexme)
+		if err != nil {
+			return NilValue, RunTimeError{expr.Name, err.Error(), nil}
+		}
+		return ObjectValue(method), nil
+	}
+	return instance.Get(expr.Name)
+}
+
+// VisitSetExpr implements ExprVisitor.
+func (i *Interpreter) VisitSetExpr(expr *SetExpr) (Value, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return NilValue, err
+	}
+	instance, ok := object.AsObject().(*LoxInstance)
+	if !ok {
+		return NilValue, RunTimeError{expr.Name, "Only instances have fields", nil}
+	}
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return NilValue, err
+	}
+	instance.Set(expr.Name, value)
+	return value, nil
+}
+
+// VisitCompoundSetExpr implements ExprVisitor.
+func (i *Interpreter) VisitCompoundSetExpr(expr *CompoundSetExpr) (Value, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return NilValue, err
+	}
+	instance, ok := object.AsObject().(*LoxInstance)
+	if !ok {
+		return NilValue, RunTimeError{expr.Name, "Only instances have properties", nil}
+	}
+	current, err := instance.Get(expr.Name)
+	if err != nil {
+		return NilValue, err
+	}
+	operand, err := i.evaluate(expr.Value)
+	if err != nil {
+		return NilValue, err
+	}
+	value, err := binaryOp(expr.Operator.Type, current, operand)
+	if err != nil {
+		return NilValue, RunTimeError{expr.Operator, err.Error(), nil}
+	}
+	instance.Set(expr.Name, value)
+	return value, nil
+}
+
+// VisitFunctionExpr implements ExprVisitor.
+func (i *Interpreter) VisitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	return ObjectValue(LoxFunction{expr.Function, i.Enviorment, false}), nil
+}
+
+// VisitListExpr implements ExprVisitor.
+func (i *Interpreter) VisitListExpr(expr *ListExpr) (Value, error) {
+	elements := make([]Value, len(expr.Elements))
+	for idx, element := range expr.Elements {
+		value, err := i.evaluate(element)
+		if err != nil {
+			return NilValue, err
+		}
+		elements[idx] = value
+	}
+	return ObjectValue(NewList(elements...)), nil
+}
+
+// VisitMapExpr implements ExprVisitor.
+func (i *Interpreter) VisitMapExpr(expr *MapExpr) (Value, error) {
+	m := NewMap()
+	for idx := range expr.Keys {
+		key, err := i.evaluate(expr.Keys[idx])
+		if err != nil {
+			return NilValue, err
+		}
+		value, err := i.evaluate(expr.Values[idx])
+		if err != nil {
+			return NilValue, err
+		}
+		if err := m.Set(key, value); err != nil {
+			return NilValue, RunTimeError{expr.Brace, err.Error(), nil}
+		}
+	}
+	return ObjectValue(m), nil
+}
+
+// VisitIndexExpr implements ExprVisitor.
+func (i *Interpreter) VisitIndexExpr(expr *IndexExpr) (Value, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return NilValue, err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return NilValue, err
+	}
+	value, err := getIndex(object, index)
+	if err != nil {
+		return NilValue, RunTimeError{expr.Bracket, err.Error(), nil}
+	}
+	return value, nil
+}
+
+// VisitIndexSetExpr implements ExprVisitor.
+func (i *Interpreter) VisitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return NilValue, err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return NilValue, err
+	}
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return NilValue, err
+	}
+	if err := setIndex(object, index, value); err != nil {
+		return NilValue, RunTimeError{expr.Bracket, err.Error(), nil}
+	}
+	return value, nil
+}
+
+// VisitCompoundIndexSetExpr implements ExprVisitor.
+func (i *Interpreter) VisitCompoundIndexSetExpr(expr *CompoundIndexSetExpr) (Value, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return NilValue, err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return NilValue, err
+	}
+	current, err := getIndex(object, index)
+	if err != nil {
+		return NilValue, RunTimeError{expr.Bracket, err.Error(), nil}
+	}
+	operand, err := i.evaluate(expr.Value)
+	if err != nil {
+		return NilValue, err
+	}
+	value, err := binaryOp(expr.Operator.Type, current, operand)
+	if err != nil {
+		return NilValue, RunTimeError{expr.Operator, err.Error(), nil}
+	}
+	if err := setIndex(object, index, value); err != nil {
+		return NilValue, RunTimeError{expr.Bracket, err.Error(), nil}
+	}
+	return value, nil
+}
+
+// VisitThisExpr implements ExprVisitor.
+func (i *Interpreter) VisitThisExpr(expr *ThisExpr) (Value, error) {
+	return i.lookUpVariable(expr.Keyword, expr)
+}
+
+// VisitReturnStmt implements StmtVisitor.
+func (i *Interpreter) VisitReturnStmt(expr *ReturnStmt) (_ any, err error) {
+	var value Value
+	if expr.Value != nil {
+		value, err = i.evaluate(expr.Value)
+		if err != nil {
+			return
+		}
+	}
+	return nil, returnValue{value}
+}
+
 // VisitCallExpr implements ExprVisitor.
-func (i *Interpreter) VisitCallExpr(expr CallExpr) (_ any, err error) {
+func (i *Interpreter) VisitCallExpr(expr *CallExpr) (_ Value, err error) {
 	callee, err := i.evaluate(expr.Callee)
 	if err != nil {
 		return
 	}
 
-	args := []any{}
+	args := make([]Value, 0, len(expr.Arguments))
 	for _, arg := range expr.Arguments {
-		var res any
+		var res Value
 		res, err = i.evaluate(arg)
 		if err != nil {
 			return
@@ -40,61 +321,230 @@ func (i *Interpreter) VisitCallExpr(expr CallExpr) (_ any, err error) {
 		args = append(args, res)
 	}
 
-	function, ok := callee.(LoxCallable)
+	function, ok := callee.AsObject().(LoxCallable)
 	if !ok {
-		err = RunTimeError{expr.Paren, "Can only call functions and classes"}
+		err = RunTimeError{expr.Paren, "Can only call functions and classes", nil}
 		return
 	}
-	if len(args) != function.Arity() {
-		err = RunTimeError{expr.Paren, fmt.Sprint("Expected", function.Arity(), "arguments but got", len(args))}
-		return
+	if _, ok := function.(*NativeFunction); ok {
+		if err := checkArity(function, expr.Paren, len(args)); err != nil {
+			return NilValue, err
+		}
+		result, err := function.Call(i, args)
+		if err != nil {
+			err = RunTimeError{expr.Paren, err.Error(), nil}
+		}
+		return result, err
+	}
+	return i.callFunction(function, expr.Paren, args)
+}
+
+// callFunction calls a lox function or class from site and records the call
+// for stack traces. Every call of lox code goes through here, also the ones
+// the interpreter makes itself for toString and the iterator protocol.
+func (i *Interpreter) callFunction(function LoxCallable, site Token, args []Value) (Value, error) {
+	if err := checkArity(function, site, len(args)); err != nil {
+		return NilValue, err
+	}
+	// the script takes up a frame too, like in the vm
+	if len(i.frames)+1 == maxFrames {
+		return NilValue, RunTimeError{site, "Stack overflow", nil}
+	}
+	i.frames = append(i.frames, callSite{frameName(function), site})
+	defer func() { i.frames = i.frames[:len(i.frames)-1] }()
+
+	result, err := function.Call(i, args)
+	if rt, ok := err.(RunTimeError); ok && rt.trace == nil {
+		rt.trace = i.traceback(rt.t)
+		err = rt
+	}
+	return result, err
+}
+
+func checkArity(function LoxCallable, site Token, argCount int) error {
+	if arity := function.Arity(); arity >= 0 && argCount != arity {
+		return RunTimeError{site, fmt.Sprintf("Expected %v arguments but got %v", arity, argCount), nil}
+	}
+	return nil
+}
+
+// traceback turns the active calls into stack frames, the innermost one is
+// at the token that raised the error and each caller is at its call site.
+func (i *Interpreter) traceback(at Token) []StackFrame {
+	trace := make([]StackFrame, 0, len(i.frames)+1)
+	for idx := len(i.frames) - 1; idx >= 0; idx-- {
+		trace = append(trace, StackFrame{i.frames[idx].function, at.Span()})
+		at = i.frames[idx].paren
 	}
-	return function.Call(i, args)
+	return append(trace, StackFrame{"script", at.Span()})
+}
+
+func frameName(function LoxCallable) string {
+	switch function := function.(type) {
+	case LoxFunction:
+		return function.declaration.Name.Lexme
+	case *LoxClass:
+		return "init"
+	}
+	return function.String()
 }
 
 // VisitWhileStmt implements StmtVisitor.
-func (i *Interpreter) VisitWhileStmt(expr WhileStmt) (_ any, err error) {
-	var res any
+func (i *Interpreter) VisitWhileStmt(expr *WhileStmt) (_ any, err error) {
+	var res Value
 	for {
 		res, err = i.evaluate(expr.Condition)
 		if err != nil {
 			return
 		}
-		if !i.isTruthy(res) {
+		if !res.IsTruthy() {
 			break
 		}
-		_, err = i.execute(expr.Body)
-		if err != nil {
+		if brk, err := i.executeLoopBody(expr.Body); brk || err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// VisitForStmt implements StmtVisitor.
+func (i *Interpreter) VisitForStmt(expr *ForStmt) (_ any, err error) {
+	prev := i.Enviorment
+	i.Enviorment = &Enviorment{i.Enviorment, map[string]Value{}}
+	defer func() { i.Enviorment = prev }()
+
+	if expr.Initializer != nil {
+		if _, err = i.execute(expr.Initializer); err != nil {
 			return
 		}
 	}
+	for {
+		if expr.Condition != nil {
+			cond, err := i.evaluate(expr.Condition)
+			if err != nil {
+				return nil, err
+			}
+			if !cond.IsTruthy() {
+				break
+			}
+		}
+		if brk, err := i.executeLoopBody(expr.Body); brk || err != nil {
+			return nil, err
+		}
+		if expr.Increment != nil {
+			if _, err = i.evaluate(expr.Increment); err != nil {
+				return
+			}
+		}
+	}
 	return
 }
 
-func (i *Interpreter) VisitLogicalExpr(expr LogicalExpr) (any, error) {
-	left, err := i.evaluate(expr.Left)
+// VisitForInStmt implements StmtVisitor.
+func (i *Interpreter) VisitForInStmt(expr *ForInStmt) (any, error) {
+	iterable, err := i.evaluate(expr.Iterable)
 	if err != nil {
 		return nil, err
 	}
+	it, err := i.iterate(iterable, expr.In)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		value, ok, err := it.next()
+		if !ok || err != nil {
+			return nil, err
+		}
+		// every iteration gets a fresh variable so closures capture the
+		// value of their own iteration
+		prev := i.Enviorment
+		i.Enviorment = &Enviorment{prev, map[string]Value{expr.Name.Lexme: value}}
+		brk, err := i.executeLoopBody(expr.Body)
+		i.Enviorment = prev
+		if brk || err != nil {
+			return nil, err
+		}
+	}
+}
+
+// iterate returns an iterator over v for a for-in loop. Instances are
+// iterated through their iter() method, which returns something iterable,
+// or are iterators themselves with a next() method.
+func (i *Interpreter) iterate(v Value, in Token) (iterator, error) {
+	if it, ok := iterate(v); ok {
+		return it, nil
+	}
+	instance, ok := v.AsObject().(*LoxInstance)
+	if !ok {
+		return nil, RunTimeError{in, notIterable, nil}
+	}
+	if iter, ok := instance.class.findMethod("iter"); ok {
+		result, err := i.callFunction(iter.bind(instance), in, nil)
+		if err != nil {
+			return nil, err
+		}
+		if it, ok := iterate(result); ok {
+			return it, nil
+		}
+		if instance, ok = result.AsObject().(*LoxInstance); !ok {
+			return nil, RunTimeError{in, notIterable, nil}
+		}
+	}
+	next, ok := instance.class.findMethod("next")
+	if !ok {
+		return nil, RunTimeError{in, notIterable, nil}
+	}
+	next = next.bind(instance)
+	return methodIterator(func() (Value, error) { return i.callFunction(next, in, nil) }), nil
+}
+
+// executeLoopBody runs one iteration of a loop and reports whether a break
+// statement ended the loop. A continue statement just ends the iteration.
+func (i *Interpreter) executeLoopBody(body Stmt) (bool, error) {
+	_, err := i.execute(body)
+	switch err.(type) {
+	case breakLoop:
+		return true, nil
+	case continueLoop:
+		return false, nil
+	}
+	return false, err
+}
+
+// VisitBreakStmt implements StmtVisitor.
+func (i *Interpreter) VisitBreakStmt(expr *BreakStmt) (any, error) {
+	return nil, breakLoop{}
+}
+
+// VisitContinueStmt implements StmtVisitor.
+func (i *Interpreter) VisitContinueStmt(expr *ContinueStmt) (any, error) {
+	return nil, continueLoop{}
+}
+
+func (i *Interpreter) VisitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	left, err := i.evaluate(expr.Left)
+	if err != nil {
+		return NilValue, err
+	}
 
 	if expr.Operator.Type == OR {
-		if i.isTruthy(left) {
+		if left.IsTruthy() {
 			return left, nil
 		}
 	} else {
-		if !i.isTruthy(left) {
+		if !left.IsTruthy() {
 			return left, nil
 		}
 	}
 	return i.evaluate(expr.Right)
 }
 
-func (i *Interpreter) VisitIfStmt(expr IfStmt) (_ any, err error) {
+func (i *Interpreter) VisitIfStmt(expr *IfStmt) (_ any, err error) {
 	ret, err := i.evaluate(expr.Condition)
 	if err != nil {
 		return
 	}
-	if i.isTruthy(ret) {
+	if ret.IsTruthy() {
 		_, err = i.execute(expr.ThenBranch)
 	} else {
 		_, err = i.execute(expr.ElseBranch)
@@ -102,39 +552,44 @@ func (i *Interpreter) VisitIfStmt(expr IfStmt) (_ any, err error) {
 	return
 }
 
-func (i *Interpreter) VisitBlock(expr Block) (_ any, err error) {
-	err = i.executeBlock(expr.Stmts, &Enviorment{i.Enviorment, map[string]any{}})
+func (i *Interpreter) VisitBlock(expr *Block) (_ any, err error) {
+	err = i.executeBlock(expr.Stmts, &Enviorment{i.Enviorment, map[string]Value{}})
 	return
 }
 
 func (i *Interpreter) executeBlock(stmts []Stmt, env *Enviorment) (err error) {
 	prev := i.Enviorment
 	i.Enviorment = env
+	defer func() { i.Enviorment = prev }()
 	for _, stmt := range stmts {
 		_, err = i.execute(stmt)
 		if err != nil {
 			return err
 		}
 	}
-	i.Enviorment = prev
 	return
 }
 
-func (i *Interpreter) VisitAssignExpr(expr AssignExpr) (value any, err error) {
+func (i *Interpreter) VisitAssignExpr(expr *AssignExpr) (value Value, err error) {
 	value, err = i.evaluate(expr.Value)
 	if err != nil {
 		return
 	}
-	err = i.Assign(expr.Name, value)
+	distance, ok := i.locals[expr]
+	if ok {
+		i.AssignAt(distance, expr.Name, value)
+	} else {
+		err = i.globals.Assign(expr.Name, value)
+	}
 	return
 }
 
-func (i *Interpreter) VisitVariableExpr(expr VariableExpr) (any, error) {
-	return i.Get(expr.Name)
+func (i *Interpreter) VisitVariableExpr(expr *VariableExpr) (Value, error) {
+	return i.lookUpVariable(expr.Name, expr)
 }
 
-func (i *Interpreter) VisitVarDecl(expr VarDecl) (_ any, err error) {
-	var value any
+func (i *Interpreter) VisitVarDecl(expr *VarDecl) (_ any, err error) {
+	var value Value
 	if expr.Initializer != nil {
 		value, err = i.evaluate(expr.Initializer)
 		if err != nil {
@@ -145,120 +600,103 @@ func (i *Interpreter) VisitVarDecl(expr VarDecl) (_ any, err error) {
 	return
 }
 
-func (i *Interpreter) VisitExprStmt(expr ExprStmt) (any, error) {
+func (i *Interpreter) VisitExprStmt(expr *ExprStmt) (any, error) {
 	_, err := i.evaluate(expr.Expr)
 	return nil, err
 }
 
-func (i *Interpreter) VisitPrintStmt(expr PrintStmt) (_ any, err error) {
+func (i *Interpreter) VisitPrintStmt(expr *PrintStmt) (_ any, err error) {
 	v, err := i.evaluate(expr.Expr)
 	if err != nil {
 		return
 	}
-	fmt.Println(v)
+	text, err := i.stringify(v, expr.Keyword)
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintln(i.stdout, text)
 	return
 }
 
-func (i *Interpreter) VisitBinaryExpr(expr BinaryExpr) (any, error) {
+// VisitStringifyExpr implements ExprVisitor.
+func (i *Interpreter) VisitStringifyExpr(expr *StringifyExpr) (Value, error) {
+	v, err := i.evaluate(expr.Expr)
+	if err != nil {
+		return NilValue, err
+	}
+	text, err := i.stringify(v, expr.Part)
+	if err != nil {
+		return NilValue, err
+	}
+	return ObjectValue(text), nil
+}
+
+// stringify formats v like Value.String but lets instances, also inside of
+// collections, provide their own representation through a toString method.
+// The methods are called from at.
+func (i *Interpreter) stringify(v Value, at Token) (string, error) {
+	return formatValue(v, func(v Value) (string, error) { return i.toString(v, at) })
+}
+
+func (i *Interpreter) toString(v Value, at Token) (string, error) {
+	instance, ok := v.AsObject().(*LoxInstance)
+	if !ok {
+		return v.String(), nil
+	}
+	method, ok := instance.class.findMethod("toString")
+	if !ok {
+		return v.String(), nil
+	}
+	result, err := i.callFunction(method.bind(instance), at, nil)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+func (i *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (Value, error) {
 	left, err := i.evaluate(expr.Left)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 	right, err := i.evaluate(expr.Right)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
-	switch expr.Operator.Type {
-	case EQUAL_EQUAL:
-		return left == right, nil
-	case BANG_EQUAL:
-		return left != right, nil
-	}
-	switch left.(type) {
-	case float64:
-		left := left.(float64)
-		right_num, ok := right.(float64)
-		if !ok {
-			return nil, RunTimeError{m: "binary expr with a left num must have a right num", t: expr.Operator}
-		}
-		switch expr.Operator.Type {
-		case PLUS:
-			return left + right_num, nil
-		case MINUS:
-			return left - right_num, nil
-		case SLASH:
-			if right_num == 0 {
-				return nil, RunTimeError{m: "cannot divide by 0", t: expr.Operator}
-			}
-			return left / right_num, nil
-		case STAR:
-			return left * right_num, nil
-		case GREATER:
-			return left > right_num, nil
-		case GREATER_EQUAL:
-			return left >= right_num, nil
-		case LESS:
-			return left < right_num, nil
-		case LESS_EQUAL:
-			return left <= right_num, nil
-		default:
-			panic("Unreachable")
-		}
-	case string:
-		left, _ := left.(string)
-		right, ok := right.(string)
-		if !ok {
-			return nil, RunTimeError{m: "binary epxr with a left string must have a right string", t: expr.Operator}
-		}
-		if expr.Operator.Type != PLUS {
-			return nil, RunTimeError{m: "binary expr on strings only support +", t: expr.Operator}
-		}
-		return left + right, nil
-	default:
-		return nil, RunTimeError{m: "binary expr does not accept this type", t: expr.Operator}
+	value, err := binaryOp(expr.Operator.Type, left, right)
+	if err != nil {
+		return NilValue, RunTimeError{m: err.Error(), t: expr.Operator}
 	}
+	return value, nil
 }
 
-func (i *Interpreter) VisitGroupingExpr(expr GroupingExpr) (any, error) {
+func (i *Interpreter) VisitGroupingExpr(expr *GroupingExpr) (Value, error) {
 	return i.evaluate(expr.Expr)
 }
 
-func (i *Interpreter) VisitLiteralExpr(expr LiteralExpr) (any, error) {
-	return expr.Value, nil
+func (i *Interpreter) VisitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	return valueOf(expr.Value), nil
 }
 
-func (i *Interpreter) VisitUnaryExpr(expr UnaryExpr) (any, error) {
+func (i *Interpreter) VisitUnaryExpr(expr *UnaryExpr) (Value, error) {
 	right, err := i.evaluate(expr.Expr)
 	if err != nil {
-		return nil, err
+		return NilValue, err
 	}
 
 	switch expr.Operator.Type {
 	case BANG:
-		return i.isTruthy(right), nil
-	case MINUS:
-		v, ok := right.(float64)
-		if !ok {
-			return nil, RunTimeError{m: "negation can only be done on numbers", t: expr.Operator}
-		}
-		return -v, nil
+		return BoolValue(!right.IsTruthy()), nil
 	default:
-		panic("Unreachable")
-	}
-}
-
-func (i *Interpreter) isTruthy(value any) bool {
-	switch value := value.(type) {
-	case nil:
-		return false
-	case bool:
-		return value
-	default:
-		return true
+		value, err := unaryOp(expr.Operator.Type, right)
+		if err != nil {
+			return NilValue, RunTimeError{m: err.Error(), t: expr.Operator}
+		}
+		return value, nil
 	}
 }
 
-func (i *Interpreter) evaluate(expr Expr) (any, error) {
+func (i *Interpreter) evaluate(expr Expr) (Value, error) {
 	return expr.Accept(i)
 }
 
@@ -269,18 +707,26 @@ func (i *Interpreter) execute(stmt Stmt) (any, error) {
 	return stmt.Accept(i)
 }
 func Interpret(e []Stmt) error {
-	i := &Interpreter{
-		&Enviorment{
-			values:    map[string]any{},
-			enclosing: nil,
-		},
-	}
-	i.Put("clock", ClockFunc{})
-	for _, v := range e {
+	i := newInterpreter()
+	errs := Resolve(i, e)
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	_, err := i.Interpret(e)
+	return err
+}
+
+// Interpret executes the resolved statements and returns the value of the
+// last statement when it is an expression statement.
+func (i *Interpreter) Interpret(e []Stmt) (Value, error) {
+	for idx, v := range e {
+		if expr, ok := v.(*ExprStmt); ok && idx == len(e)-1 {
+			return i.evaluate(expr.Expr)
+		}
 		_, err := i.execute(v)
 		if err != nil {
-			return err
+			return NilValue, err
 		}
 	}
-	return nil
+	return NilValue, nil
 }
@@ -0,0 +1,125 @@
+package glox
+
+import (
+	"errors"
+	"fmt"
+)
+
+// notIterable is the runtime error for a for-in loop over a value that can't
+// be iterated.
+const notIterable = "Only lists, maps, strings, ranges and instances with an iter() or next() method can be iterated"
+
+// iterator produces the values a for-in loop runs over, ok is false once it
+// is exhausted.
+type iterator interface {
+	next() (value Value, ok bool, err error)
+}
+
+// iterate returns an iterator over a builtin value: the elements of a list,
+// the keys of a map, the characters of a string or the numbers of a range.
+// Instances implement the iterator protocol with lox methods, which only the
+// interpreter and the vm can call, so they are handled there.
+func iterate(v Value) (iterator, bool) {
+	switch object := v.AsObject().(type) {
+	case *LoxList:
+		return &listIterator{object, 0}, true
+	case *LoxMap:
+		keys := append([]Value(nil), object.keys...)
+		return &listIterator{NewList(keys...), 0}, true
+	case string:
+		return &stringIterator{[]rune(object), 0}, true
+	case *LoxRange:
+		return &rangeIterator{object, object.start}, true
+	}
+	return nil, false
+}
+
+// listIterator walks a list by index, so elements pushed while iterating are
+// visited too.
+type listIterator struct {
+	list *LoxList
+	idx  int
+}
+
+func (it *listIterator) next() (Value, bool, error) {
+	if it.idx >= len(it.list.elements) {
+		return NilValue, false, nil
+	}
+	it.idx++
+	return it.list.elements[it.idx-1], true, nil
+}
+
+type stringIterator struct {
+	runes []rune
+	idx   int
+}
+
+func (it *stringIterator) next() (Value, bool, error) {
+	if it.idx >= len(it.runes) {
+		return NilValue, false, nil
+	}
+	it.idx++
+	return ObjectValue(string(it.runes[it.idx-1])), true, nil
+}
+
+type rangeIterator struct {
+	r       *LoxRange
+	current int64
+}
+
+func (it *rangeIterator) next() (Value, bool, error) {
+	if it.r.step > 0 && it.current >= it.r.end || it.r.step < 0 && it.current <= it.r.end {
+		return NilValue, false, nil
+	}
+	value := it.current
+	it.current += it.r.step
+	if it.r.step > 0 && it.current < value || it.r.step < 0 && it.current > value {
+		// stepping past the largest integer ends the range
+		it.current = it.r.end
+	}
+	return IntValue(value), true, nil
+}
+
+// methodIterator calls the next() method of a user iterator, which returns
+// nil once it is exhausted.
+type methodIterator func() (Value, error)
+
+func (next methodIterator) next() (Value, bool, error) {
+	value, err := next()
+	if err != nil {
+		return NilValue, false, err
+	}
+	return value, !value.IsNil(), nil
+}
+
+// LoxRange is the sequence of integers from start up to but not including
+// end, counting by step.
+type LoxRange struct {
+	start, end, step int64
+}
+
+func (r *LoxRange) String() string {
+	return fmt.Sprintf("range(%v, %v, %v)", r.start, r.end, r.step)
+}
+
+var rangeFunction = &NativeFunction{"range", -1, func(args []Value) (Value, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return NilValue, fmt.Errorf("Expected 1 to 3 arguments but got %v", len(args))
+	}
+	for _, arg := range args {
+		if !arg.IsInt() {
+			return NilValue, fmt.Errorf("range arguments must be integers but got %v", arg)
+		}
+	}
+	r := &LoxRange{0, args[0].AsInt(), 1}
+	if len(args) >= 2 {
+		r.start, r.end = args[0].AsInt(), args[1].AsInt()
+	}
+	if len(args) == 3 {
+		r.step = args[2].AsInt()
+	}
+	if r.step == 0 {
+		return NilValue, errors.New("range step must not be zero")
+	}
+	return ObjectValue(r), nil
+}}
@@ -0,0 +1,230 @@
+package glox
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LoxList is the runtime representation of a lox list, shared by the
+// interpreter and the bytecode vm.
+type LoxList struct {
+	elements []Value
+}
+
+func NewList(elements ...Value) *LoxList {
+	return &LoxList{elements}
+}
+
+func (l *LoxList) Elements() []Value {
+	return l.elements
+}
+
+func (l *LoxList) String() string {
+	text, _ := formatValue(ObjectValue(l), func(v Value) (string, error) { return v.String(), nil })
+	return text
+}
+
+// formatValue formats v like print does, strings inside collections are
+// quoted and every other element is formatted with str. Collections that
+// contain themselves print the inner reference as [...] or {...}.
+func formatValue(v Value, str func(Value) (string, error)) (string, error) {
+	return formatNested(v, str, map[any]bool{})
+}
+
+func formatNested(v Value, str func(Value) (string, error), seen map[any]bool) (string, error) {
+	var open, close string
+	var keys, values []Value
+	switch object := v.AsObject().(type) {
+	case *LoxList:
+		open, close, values = "[", "]", object.elements
+	case *LoxMap:
+		open, close, keys, values = "{", "}", object.keys, object.values
+	default:
+		return str(v)
+	}
+	if seen[v.AsObject()] {
+		return open + "..." + close, nil
+	}
+	seen[v.AsObject()] = true
+	defer delete(seen, v.AsObject())
+
+	b := &strings.Builder{}
+	b.WriteString(open)
+	for idx, element := range values {
+		if idx > 0 {
+			b.WriteString(", ")
+		}
+		if keys != nil {
+			text, err := formatElement(keys[idx], str, seen)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(text)
+			b.WriteString(": ")
+		}
+		text, err := formatElement(element, str, seen)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(text)
+	}
+	b.WriteString(close)
+	return b.String(), nil
+}
+
+func formatElement(element Value, str func(Value) (string, error), seen map[any]bool) (string, error) {
+	if element.IsString() {
+		return strconv.Quote(element.AsString()), nil
+	}
+	return formatNested(element, str, seen)
+}
+
+// index turns a lox index into a position in the list, negative indices
+// count from the end. end allows the position just past the last element.
+func (l *LoxList) index(index Value, end bool) (int, error) {
+	if !index.IsInt() {
+		return 0, fmt.Errorf("list index must be an integer but got %v", index)
+	}
+	idx, length := index.AsInt(), int64(len(l.elements))
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx > length || idx == length && !end {
+		return 0, fmt.Errorf("list index %v out of range for length %v", index, length)
+	}
+	return int(idx), nil
+}
+
+// clamp is like index for slice bounds, which are clamped to the list.
+func (l *LoxList) clamp(index Value) (int, error) {
+	if !index.IsInt() {
+		return 0, fmt.Errorf("slice index must be an integer but got %v", index)
+	}
+	idx, length := index.AsInt(), int64(len(l.elements))
+	if idx < 0 {
+		idx += length
+	}
+	return int(min(max(idx, 0), length)), nil
+}
+
+// getIndex evaluates object[index]. Errors only carry the message, callers
+// attach the position.
+func getIndex(object, index Value) (Value, error) {
+	switch object := object.AsObject().(type) {
+	case *LoxList:
+		idx, err := object.index(index, false)
+		if err != nil {
+			return NilValue, err
+		}
+		return object.elements[idx], nil
+	case *LoxMap:
+		value, ok, err := object.Get(index)
+		if err != nil {
+			return NilValue, err
+		}
+		if !ok {
+			return NilValue, fmt.Errorf("map key %v not found", quoteKey(index))
+		}
+		return value, nil
+	}
+	return NilValue, errors.New("Only lists and maps can be indexed")
+}
+
+// setIndex evaluates object[index] = value.
+func setIndex(object, index, value Value) error {
+	switch object := object.AsObject().(type) {
+	case *LoxList:
+		idx, err := object.index(index, false)
+		if err != nil {
+			return err
+		}
+		object.elements[idx] = value
+		return nil
+	case *LoxMap:
+		return object.Set(index, value)
+	}
+	return errors.New("Only lists and maps can be indexed")
+}
+
+// getMethod returns the builtin method called name of a runtime object that
+// is not an instance, like the methods of lists and maps.
+func getMethod(object Value, name string) (*NativeFunction, error) {
+	switch object := object.AsObject().(type) {
+	case *LoxList:
+		if method, ok := object.method(name); ok {
+			return method, nil
+		}
+		return nil, fmt.Errorf("Undefined property '%v'.", name)
+	case *LoxMap:
+		if method, ok := object.method(name); ok {
+			return method, nil
+		}
+		return nil, fmt.Errorf("Undefined property '%v'.", name)
+	}
+	return nil, errors.New("Only instances have properties")
+}
+
+func (l *LoxList) method(name string) (*NativeFunction, bool) {
+	switch name {
+	case "len":
+		return &NativeFunction{name, 0, func(args []Value) (Value, error) {
+			return IntValue(int64(len(l.elements))), nil
+		}}, true
+	case "push":
+		return &NativeFunction{name, -1, func(args []Value) (Value, error) {
+			l.elements = append(l.elements, args...)
+			return NilValue, nil
+		}}, true
+	case "pop":
+		return &NativeFunction{name, 0, func(args []Value) (Value, error) {
+			if len(l.elements) == 0 {
+				return NilValue, errors.New("pop from an empty list")
+			}
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last, nil
+		}}, true
+	case "insert":
+		return &NativeFunction{name, 2, func(args []Value) (Value, error) {
+			idx, err := l.index(args[0], true)
+			if err != nil {
+				return NilValue, err
+			}
+			l.elements = append(l.elements, NilValue)
+			copy(l.elements[idx+1:], l.elements[idx:])
+			l.elements[idx] = args[1]
+			return NilValue, nil
+		}}, true
+	case "remove":
+		return &NativeFunction{name, 1, func(args []Value) (Value, error) {
+			idx, err := l.index(args[0], false)
+			if err != nil {
+				return NilValue, err
+			}
+			removed := l.elements[idx]
+			l.elements = append(l.elements[:idx], l.elements[idx+1:]...)
+			return removed, nil
+		}}, true
+	case "slice":
+		return &NativeFunction{name, -1, func(args []Value) (Value, error) {
+			if len(args) < 1 || len(args) > 2 {
+				return NilValue, fmt.Errorf("Expected 1 or 2 arguments but got %v", len(args))
+			}
+			start, err := l.clamp(args[0])
+			if err != nil {
+				return NilValue, err
+			}
+			end := len(l.elements)
+			if len(args) == 2 {
+				if end, err = l.clamp(args[1]); err != nil {
+					return NilValue, err
+				}
+			}
+			end = max(start, end)
+			return ObjectValue(NewList(append([]Value(nil), l.elements[start:end]...)...)), nil
+		}}, true
+	}
+	return nil, false
+}
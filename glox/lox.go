@@ -2,52 +2,203 @@ package glox
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
-func Repl() {
-	i := &Interpreter{
-		&Enviorment{
-			values:    map[string]any{},
-			enclosing: nil,
-		},
+type Backend int
+
+const (
+	TreeWalker Backend = iota
+	Bytecode
+)
+
+// SyntaxError collects every static error of a program, it is returned
+// instead of running code that failed to parse, resolve or compile.
+type SyntaxError struct {
+	Errors []error
+}
+
+func (e *SyntaxError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for idx, err := range e.Errors {
+		messages[idx] = err.Error()
 	}
-	reader := bufio.NewReader(os.Stdin)
-	for {
-		fmt.Print(">>> ")
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			fmt.Println("Error reading line", err)
-			os.Exit(1)
-		}
+	return strings.Join(messages, "\n")
+}
+
+func (e *SyntaxError) Unwrap() []error {
+	return e.Errors
+}
+
+// VM is an embeddable lox runtime. Globals defined by one call to Eval stay
+// visible to the following ones.
+type VM struct {
+	stdout      io.Writer
+	stderr      io.Writer
+	stdin       io.Reader
+	backend     Backend
+	renderer    DiagnosticRenderer
+	interpreter *Interpreter
+	machine     *stackVM
+	// sources holds every named input by file name, code defined by one
+	// input can fail while running another and diagnostics have to quote
+	// the input the error is in
+	sources map[string]string
+	inputs  int
+}
+
+type Option func(*VM)
+
+func WithStdout(w io.Writer) Option {
+	return func(vm *VM) { vm.stdout = w }
+}
 
-		stmts, errs := ParseCode(input)
+func WithStderr(w io.Writer) Option {
+	return func(vm *VM) { vm.stderr = w }
+}
+
+func WithStdin(r io.Reader) Option {
+	return func(vm *VM) { vm.stdin = r }
+}
+
+func WithBackend(b Backend) Option {
+	return func(vm *VM) { vm.backend = b }
+}
+
+// WithColor enables ansi colors in diagnostics written to stderr.
+func WithColor(color bool) Option {
+	return func(vm *VM) { vm.renderer.Color = color }
+}
+
+// WithDiagnosticFormat selects how diagnostics are written to stderr.
+func WithDiagnosticFormat(format DiagnosticFormat) Option {
+	return func(vm *VM) { vm.renderer.Format = format }
+}
+
+func New(opts ...Option) *VM {
+	vm := &VM{
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+		stdin:   os.Stdin,
+		backend: TreeWalker,
+		sources: map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(vm)
+	}
+
+	switch vm.backend {
+	case Bytecode:
+		vm.machine = newStackVM()
+		vm.machine.stdout = vm.stdout
+	default:
+		vm.interpreter = newInterpreter()
+		vm.interpreter.stdout = vm.stdout
+	}
+	return vm
+}
+
+// Eval runs src and returns the value of its last statement if that is an
+// expression statement, whose semicolon may be left out. Static errors are
+// returned as a *SyntaxError and runtime errors as a RunTimeError.
+func (vm *VM) Eval(src string) (Value, error) {
+	return vm.eval("", src, true)
+}
+
+// eval runs src read from file, trailingExpr lets its last expression
+// statement leave out the semicolon.
+func (vm *VM) eval(file, src string, trailingExpr bool) (Value, error) {
+	if file != "" {
+		vm.sources[file] = src
+	}
+	stmts, errs := parseFile(file, src, trailingExpr)
+	if len(errs) != 0 {
+		return NilValue, &SyntaxError{errs}
+	}
+
+	if vm.machine != nil {
+		errs = Resolve(nil, stmts)
 		if len(errs) != 0 {
-			for _, err := range errs {
-				fmt.Println(err)
-			}
-			os.Exit(1)
+			return NilValue, &SyntaxError{errs}
 		}
-		for _, stmt := range stmts {
-			_, err := i.execute(stmt)
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
+		function, errs := Compile(stmts)
+		if len(errs) != 0 {
+			return NilValue, &SyntaxError{errs}
 		}
+		return vm.machine.interpret(function)
 	}
+
+	errs = Resolve(vm.interpreter, stmts)
+	if len(errs) != 0 {
+		return NilValue, &SyntaxError{errs}
+	}
+	return vm.interpreter.Interpret(stmts)
 }
-func Run(code string) {
-	stmts, errors := ParseCode(code)
-	if len(errors) != 0 {
-		for _, e := range errors {
-			fmt.Println(e)
-		}
-		return
+
+// Run runs src and reports any error to stderr as diagnostics before
+// returning it. Every input gets its own name, <run:N>, in diagnostics.
+func (vm *VM) Run(src string) error {
+	_, err := vm.eval(vm.inputName("run"), src, false)
+	vm.report(err)
+	return err
+}
+
+// inputName names the next input that was not read from a file.
+func (vm *VM) inputName(kind string) string {
+	vm.inputs++
+	return fmt.Sprintf("<%v:%v>", kind, vm.inputs)
+}
+
+// RunFile is like Run for the code in the file at path.
+func (vm *VM) RunFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		vm.report(err)
+		return err
 	}
-	err := Interpret(stmts)
+	_, err = vm.eval(path, string(b), false)
+	vm.report(err)
+	return err
+}
+
+// report renders err to stderr, quoting the input each diagnostic is in.
+func (vm *VM) report(err error) {
 	if err != nil {
-		panic(err)
+		vm.renderer.render(vm.stderr, func(file string) (string, bool) {
+			src, ok := vm.sources[file]
+			return src, ok
+		}, Diagnostics(err)...)
 	}
 }
+
+// Repl evaluates stdin line by line, reporting errors to stderr, until the
+// input is exhausted. Line N is named <repl:N> in diagnostics.
+func (vm *VM) Repl() error {
+	reader := bufio.NewReader(vm.stdin)
+	for {
+		fmt.Fprint(vm.stdout, ">>> ")
+		input, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) && input == "" {
+			fmt.Fprintln(vm.stdout)
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		_, err = vm.eval(vm.inputName("repl"), input, false)
+		vm.report(err)
+	}
+}
+
+func Repl() error {
+	return New().Repl()
+}
+
+func Run(code string) error {
+	return New().Run(code)
+}
@@ -0,0 +1,109 @@
+package glox
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var backends = []struct {
+	name    string
+	backend Backend
+}{{"tree", TreeWalker}, {"vm", Bytecode}}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Value
+	}{
+		{"1 + 2;", NumberValue(3)},
+		{`"a" + "b";`, ObjectValue("ab")},
+		{"var x = 1;", NilValue},
+		{"print 1; !nil;", BoolValue(true)},
+		{"fun f() { return 4; } f() * 2;", NumberValue(8)},
+	}
+	for _, b := range backends {
+		for _, test := range tests {
+			got, err := New(WithBackend(b.backend), WithStdout(&bytes.Buffer{})).Eval(test.src)
+			if err != nil {
+				t.Errorf("%v: Eval(%q) failed: %v", b.name, test.src, err)
+				continue
+			}
+			if !got.Equals(test.want) {
+				t.Errorf("%v: Eval(%q) = %v, want %v", b.name, test.src, got, test.want)
+			}
+		}
+	}
+}
+
+func TestEvalTrailingExpression(t *testing.T) {
+	for _, b := range backends {
+		vm := New(WithBackend(b.backend), WithStdout(&bytes.Buffer{}), WithStderr(&bytes.Buffer{}))
+		got, err := vm.Eval("var x = 20; x * 2 + 2")
+		if err != nil || !got.Equals(IntValue(42)) {
+			t.Errorf("%v: Eval returned %v, %v, want 42", b.name, got, err)
+		}
+		for _, src := range []string{"1 2", "if (true) 1", "while (false) 1", "for (; false;) 1", "for (x; false;) 1", "{ 1"} {
+			if _, err := vm.Eval(src); err == nil {
+				t.Errorf("%v: Eval(%q) succeeded, only a top level expression may leave out its semicolon", b.name, src)
+			}
+		}
+		if err := vm.Run("print x"); err == nil {
+			t.Errorf("%v: Run accepted a statement without a semicolon", b.name)
+		}
+	}
+}
+
+func TestEvalKeepsGlobals(t *testing.T) {
+	for _, b := range backends {
+		out := &bytes.Buffer{}
+		vm := New(WithBackend(b.backend), WithStdout(out))
+		for _, src := range []string{"var n = 1;", "fun inc() { n = n + 1; }", "inc(); inc();", "print n;"} {
+			if _, err := vm.Eval(src); err != nil {
+				t.Fatalf("%v: Eval(%q) failed: %v", b.name, src, err)
+			}
+		}
+		if got := out.String(); got != "3\n" {
+			t.Errorf("%v: printed %q, want %q", b.name, got, "3\n")
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for _, b := range backends {
+		vm := New(WithBackend(b.backend), WithStdout(&bytes.Buffer{}))
+
+		_, err := vm.Eval("var = 1;\nprint (;")
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("%v: got %v, want a *SyntaxError", b.name, err)
+		}
+		if len(syntaxErr.Errors) == 0 {
+			t.Errorf("%v: the *SyntaxError holds no errors", b.name)
+		}
+
+		_, err = vm.Eval("-nil;")
+		var runtimeErr RunTimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Fatalf("%v: got %v, want a RunTimeError", b.name, err)
+		}
+	}
+}
+
+func TestRepl(t *testing.T) {
+	for _, b := range backends {
+		out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+		in := strings.NewReader("var a = 2;\nprint a * a;\nprint b;\nprint a;\n")
+		vm := New(WithBackend(b.backend), WithStdout(out), WithStderr(errOut), WithStdin(in))
+		if err := vm.Repl(); err != nil {
+			t.Fatalf("%v: Repl failed: %v", b.name, err)
+		}
+		if want := ">>> >>> 4\n>>> >>> 2\n>>> \n"; out.String() != want {
+			t.Errorf("%v: stdout is %q, want %q", b.name, out.String(), want)
+		}
+		if !strings.Contains(errOut.String(), "Undefined Variable 'b'") {
+			t.Errorf("%v: stderr is %q, want an undefined variable error", b.name, errOut.String())
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package glox
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// LoxMap is the runtime representation of a lox map, shared by the
+// interpreter and the bytecode vm. Entries keep the order they were first
+// inserted in, so iterating a map is deterministic.
+type LoxMap struct {
+	keys   []Value
+	values []Value
+	index  map[mapKey]int
+}
+
+// mapKey is the hashable form of a lox value. Values that are equal in lox
+// have the same key, in particular an integer and a float holding the same
+// number.
+type mapKey struct {
+	kind ValueType
+	bits uint64
+	obj  any
+}
+
+func NewMap() *LoxMap {
+	return &LoxMap{index: map[mapKey]int{}}
+}
+
+func (m *LoxMap) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys of m in insertion order.
+func (m *LoxMap) Keys() []Value {
+	return m.keys
+}
+
+// Values returns the values of m in the order of their keys.
+func (m *LoxMap) Values() []Value {
+	return m.values
+}
+
+func (m *LoxMap) Get(key Value) (Value, bool, error) {
+	hash, err := hashKey(key)
+	if err != nil {
+		return NilValue, false, err
+	}
+	idx, ok := m.index[hash]
+	if !ok {
+		return NilValue, false, nil
+	}
+	return m.values[idx], true, nil
+}
+
+func (m *LoxMap) Set(key, value Value) error {
+	hash, err := hashKey(key)
+	if err != nil {
+		return err
+	}
+	if idx, ok := m.index[hash]; ok {
+		m.values[idx] = value
+		return nil
+	}
+	m.index[hash] = len(m.keys)
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+	return nil
+}
+
+// Delete removes key from m and reports whether it was present.
+func (m *LoxMap) Delete(key Value) (bool, error) {
+	hash, err := hashKey(key)
+	if err != nil {
+		return false, err
+	}
+	idx, ok := m.index[hash]
+	if !ok {
+		return false, nil
+	}
+	delete(m.index, hash)
+	m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+	m.values = append(m.values[:idx], m.values[idx+1:]...)
+	for i := idx; i < len(m.keys); i++ {
+		hash, _ := hashKey(m.keys[i])
+		m.index[hash] = i
+	}
+	return true, nil
+}
+
+func (m *LoxMap) String() string {
+	text, _ := formatValue(ObjectValue(m), func(v Value) (string, error) { return v.String(), nil })
+	return text
+}
+
+// hashKey returns the key of v in the go map of a LoxMap. Nil, booleans,
+// numbers and strings hash by value, instances, classes and functions by
+// identity. Lists and maps can change after they were inserted and are not
+// hashable.
+func hashKey(v Value) (mapKey, error) {
+	switch v.Type {
+	case NIL_VALUE:
+		return mapKey{}, nil
+	case BOOL_VALUE, INT_VALUE:
+		return mapKey{v.Type, math.Float64bits(v.num), nil}, nil
+	case NUMBER_VALUE:
+		n := v.num
+		if math.IsNaN(n) {
+			return mapKey{}, errors.New("NaN can't be used as a map key")
+		}
+		if n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64 {
+			return hashKey(IntValue(int64(n)))
+		}
+		return mapKey{NUMBER_VALUE, math.Float64bits(n), nil}, nil
+	}
+	switch obj := v.obj.(type) {
+	case *LoxList:
+		return mapKey{}, errors.New("a list can't be used as a map key")
+	case *LoxMap:
+		return mapKey{}, errors.New("a map can't be used as a map key")
+	case string:
+		return mapKey{OBJECT_VALUE, 0, obj}, nil
+	}
+	if !isComparable(v.obj) {
+		return mapKey{}, fmt.Errorf("a %T can't be used as a map key", v.obj)
+	}
+	return mapKey{OBJECT_VALUE, 0, v.obj}, nil
+}
+
+// isComparable reports whether obj can be compared with == without
+// panicking.
+func isComparable(obj any) bool {
+	return obj == nil || reflect.TypeOf(obj).Comparable()
+}
+
+// quoteKey formats a map key for error messages.
+func quoteKey(key Value) string {
+	if key.IsString() {
+		return strconv.Quote(key.AsString())
+	}
+	return key.String()
+}
+
+func (m *LoxMap) method(name string) (*NativeFunction, bool) {
+	switch name {
+	case "len":
+		return &NativeFunction{name, 0, func(args []Value) (Value, error) {
+			return IntValue(int64(m.Len())), nil
+		}}, true
+	case "keys":
+		return &NativeFunction{name, 0, func(args []Value) (Value, error) {
+			return ObjectValue(NewList(append([]Value(nil), m.keys...)...)), nil
+		}}, true
+	case "values":
+		return &NativeFunction{name, 0, func(args []Value) (Value, error) {
+			return ObjectValue(NewList(append([]Value(nil), m.values...)...)), nil
+		}}, true
+	case "has":
+		return &NativeFunction{name, 1, func(args []Value) (Value, error) {
+			_, ok, err := m.Get(args[0])
+			return BoolValue(ok), err
+		}}, true
+	case "delete":
+		return &NativeFunction{name, 1, func(args []Value) (Value, error) {
+			ok, err := m.Delete(args[0])
+			return BoolValue(ok), err
+		}}, true
+	}
+	return nil, false
+}
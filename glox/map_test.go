@@ -0,0 +1,80 @@
+package glox
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMapKeys(t *testing.T) {
+	m := NewMap()
+	instance := &LoxInstance{}
+	keys := []Value{NilValue, BoolValue(true), IntValue(2), NumberValue(2.5), ObjectValue("s"), ObjectValue(instance)}
+	for idx, key := range keys {
+		if err := m.Set(key, IntValue(int64(idx))); err != nil {
+			t.Fatalf("Set(%v) failed: %v", key, err)
+		}
+	}
+	for idx, key := range keys {
+		value, ok, err := m.Get(key)
+		if err != nil || !ok || !value.Equals(IntValue(int64(idx))) {
+			t.Errorf("Get(%v) = %v, %v, %v, want %v", key, value, ok, err, idx)
+		}
+	}
+
+	// equal values are the same key
+	for _, alias := range []struct{ key, same Value }{
+		{IntValue(2), NumberValue(2)},
+		{ObjectValue("s"), ObjectValue(string([]byte{'s'}))},
+	} {
+		if _, ok, _ := m.Get(alias.same); !ok {
+			t.Errorf("%v is not found as %v", alias.key, alias.same)
+		}
+	}
+	if _, ok, _ := m.Get(ObjectValue(&LoxInstance{})); ok {
+		t.Error("instances should hash by identity")
+	}
+	if _, ok, _ := m.Get(BoolValue(false)); ok {
+		t.Error("false should not find true")
+	}
+}
+
+func TestMapKeyErrors(t *testing.T) {
+	m := NewMap()
+	for _, key := range []Value{NumberValue(math.NaN()), ObjectValue(NewList()), ObjectValue(NewMap())} {
+		if err := m.Set(key, NilValue); err == nil {
+			t.Errorf("Set(%v) succeeded, want an error", key)
+		}
+	}
+}
+
+func TestMapDeleteKeepsOrder(t *testing.T) {
+	m := NewMap()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.Set(ObjectValue(key), ObjectValue(key))
+	}
+	m.Delete(ObjectValue("b"))
+	m.Set(ObjectValue("b"), NilValue)
+	if got := m.String(); got != `{"a": "a", "c": "c", "d": "d", "b": nil}` {
+		t.Errorf("map is %v", got)
+	}
+	if value, _, _ := m.Get(ObjectValue("d")); value.AsString() != "d" {
+		t.Errorf("d maps to %v after deleting b", value)
+	}
+}
+
+func TestEqualValuesHashAlike(t *testing.T) {
+	values := []Value{
+		IntValue(9007199254740993), NumberValue(9007199254740992), IntValue(9007199254740992),
+		IntValue(math.MaxInt64), NumberValue(math.MaxInt64), IntValue(math.MinInt64), NumberValue(math.MinInt64),
+		IntValue(0), NumberValue(0), NumberValue(math.Copysign(0, -1)), NumberValue(0.5), IntValue(-3), NumberValue(-3),
+	}
+	for _, a := range values {
+		for _, b := range values {
+			keyA, _ := hashKey(a)
+			keyB, _ := hashKey(b)
+			if a.Equals(b) != (keyA == keyB) {
+				t.Errorf("%v == %v is %v but their keys are %v and %v", a, b, a.Equals(b), keyA, keyB)
+			}
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package glox
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// NativeFunc is the raw signature of a go function callable from lox. It
+// receives the evaluated arguments and accepts any number of them.
+type NativeFunc func(args []Value) (Value, error)
+
+// NativeFunction is a go function exposed to lox. An arity of -1 means the
+// function is variadic and checks the number of arguments itself.
+type NativeFunction struct {
+	name  string
+	arity int
+	fn    NativeFunc
+}
+
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+func (n *NativeFunction) Call(i *Interpreter, args []Value) (Value, error) {
+	return n.fn(args)
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+var (
+	valueType = reflect.TypeOf(Value{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Define registers fn as a global lox function called name. fn is either a
+// NativeFunc, which is variadic and gets the raw lox values, or any go
+// function whose parameters are converted from lox values and whose results
+// are converted back. Supported parameter and result types are bool, string,
+// the numeric types, any, Value and slices and maps of these, which are lox
+// lists and maps. A trailing error result is reported as a runtime error and
+// variadic go functions are variadic in lox too.
+func (vm *VM) Define(name string, fn any) error {
+	native, err := newNativeFunction(name, fn)
+	if err != nil {
+		return err
+	}
+	vm.defineGlobal(name, ObjectValue(native))
+	return nil
+}
+
+func (vm *VM) defineGlobal(name string, value Value) {
+	if vm.machine != nil {
+		vm.machine.globals[name] = value
+	} else {
+		vm.interpreter.globals.Put(name, value)
+	}
+}
+
+func newNativeFunction(name string, fn any) (*NativeFunction, error) {
+	switch fn := fn.(type) {
+	case NativeFunc:
+		return &NativeFunction{name, -1, fn}, nil
+	case func(args []Value) (Value, error):
+		return &NativeFunction{name, -1, fn}, nil
+	}
+
+	rv := reflect.ValueOf(fn)
+	t := rv.Type()
+	if t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("cannot define %v: %v is not a function", name, t)
+	}
+	for idx := range t.NumIn() {
+		param := t.In(idx)
+		if t.IsVariadic() && idx == t.NumIn()-1 {
+			param = param.Elem()
+		}
+		if err := checkNativeType(param); err != nil {
+			return nil, fmt.Errorf("cannot define %v: parameter %d: %w", name, idx+1, err)
+		}
+	}
+	results := t.NumOut()
+	if results > 0 && t.Out(results-1) == errorType {
+		results--
+	}
+	if results > 1 {
+		return nil, fmt.Errorf("cannot define %v: too many results", name)
+	}
+	if results == 1 {
+		if err := checkNativeType(t.Out(0)); err != nil {
+			return nil, fmt.Errorf("cannot define %v: result: %w", name, err)
+		}
+	}
+
+	arity := t.NumIn()
+	minArgs := arity
+	if t.IsVariadic() {
+		arity = -1
+		minArgs--
+	}
+
+	call := func(args []Value) (Value, error) {
+		if len(args) < minArgs {
+			return NilValue, fmt.Errorf("Expected at least %v arguments but got %v", minArgs, len(args))
+		}
+		in := make([]reflect.Value, len(args))
+		for idx, arg := range args {
+			var param reflect.Type
+			if t.IsVariadic() && idx >= minArgs {
+				param = t.In(t.NumIn() - 1).Elem()
+			} else {
+				param = t.In(idx)
+			}
+			converted, err := toGo(arg, param)
+			if err != nil {
+				return NilValue, fmt.Errorf("argument %d of %v: %w", idx+1, name, err)
+			}
+			in[idx] = converted
+		}
+
+		out := rv.Call(in)
+		if len(out) > 0 && t.Out(len(out)-1) == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return NilValue, err
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return NilValue, nil
+		}
+		return fromGo(out[0])
+	}
+	return &NativeFunction{name, arity, call}, nil
+}
+
+func checkNativeType(t reflect.Type) error {
+	if t == valueType {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nil
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return nil
+		}
+	case reflect.Slice:
+		return checkNativeType(t.Elem())
+	case reflect.Map:
+		if err := checkNativeType(t.Key()); err != nil {
+			return err
+		}
+		return checkNativeType(t.Elem())
+	}
+	return fmt.Errorf("unsupported type %v", t)
+}
+
+// toGo converts a lox value into a go value of type t.
+func toGo(v Value, t reflect.Type) (reflect.Value, error) {
+	if t == valueType {
+		return reflect.ValueOf(v), nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if !v.IsBool() {
+			return reflect.Value{}, fmt.Errorf("expected a bool but got %v", v)
+		}
+		return reflect.ValueOf(v.AsBool()).Convert(t), nil
+	case reflect.String:
+		if !v.IsString() {
+			return reflect.Value{}, fmt.Errorf("expected a string but got %v", v)
+		}
+		return reflect.ValueOf(v.AsString()).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		if !v.IsNumber() {
+			return reflect.Value{}, fmt.Errorf("expected a number but got %v", v)
+		}
+		return reflect.ValueOf(v.AsNumber()).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !v.IsInt() && (!v.IsNumber() || v.AsNumber() != math.Trunc(v.AsNumber())) {
+			return reflect.Value{}, fmt.Errorf("expected an integer but got %v", v)
+		}
+		var n int64
+		if v.IsInt() {
+			n = v.AsInt()
+		} else {
+			if f := v.AsNumber(); f < math.MinInt64 || f >= math.MaxInt64 {
+				return reflect.Value{}, fmt.Errorf("%v out of range for %v", v, t)
+			}
+			n = int64(v.AsNumber())
+		}
+		result := reflect.New(t).Elem()
+		if result.OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("%v out of range for %v", v, t)
+		}
+		result.SetInt(n)
+		return result, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !v.IsInt() && (!v.IsNumber() || v.AsNumber() != math.Trunc(v.AsNumber())) {
+			return reflect.Value{}, fmt.Errorf("expected an integer but got %v", v)
+		}
+		var n uint64
+		if v.IsInt() {
+			if v.AsInt() < 0 {
+				return reflect.Value{}, fmt.Errorf("%v out of range for %v", v, t)
+			}
+			n = uint64(v.AsInt())
+		} else {
+			if f := v.AsNumber(); f < 0 || f >= math.MaxUint64 {
+				return reflect.Value{}, fmt.Errorf("%v out of range for %v", v, t)
+			}
+			n = uint64(v.AsNumber())
+		}
+		result := reflect.New(t).Elem()
+		if result.OverflowUint(n) {
+			return reflect.Value{}, fmt.Errorf("%v out of range for %v", v, t)
+		}
+		result.SetUint(n)
+		return result, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(v.ToGo()), nil
+	case reflect.Slice:
+		list, ok := v.AsObject().(*LoxList)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a list but got %v", v)
+		}
+		slice := reflect.MakeSlice(t, len(list.elements), len(list.elements))
+		for idx, element := range list.elements {
+			converted, err := toGo(element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", idx, err)
+			}
+			slice.Index(idx).Set(converted)
+		}
+		return slice, nil
+	case reflect.Map:
+		m, ok := v.AsObject().(*LoxMap)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a map but got %v", v)
+		}
+		result := reflect.MakeMapWithSize(t, m.Len())
+		for idx, key := range m.keys {
+			k, err := toGo(key, t.Key())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %v: %w", quoteKey(key), err)
+			}
+			value, err := toGo(m.values[idx], t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %v: %w", quoteKey(key), err)
+			}
+			result.SetMapIndex(k, value)
+		}
+		return result, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported type %v", t)
+}
+
+// fromGo converts the result of a go function into a lox value.
+func fromGo(rv reflect.Value) (Value, error) {
+	if !rv.IsValid() {
+		return NilValue, nil
+	}
+	if rv.Type() == valueType {
+		return rv.Interface().(Value), nil
+	}
+	if isRuntimeObject(rv.Interface()) && !(rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return ObjectValue(rv.Interface()), nil
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return BoolValue(rv.Bool()), nil
+	case reflect.String:
+		return ObjectValue(rv.String()), nil
+	case reflect.Float32, reflect.Float64:
+		return NumberValue(rv.Float()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return IntValue(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return NumberValue(float64(rv.Uint())), nil
+		}
+		return IntValue(int64(rv.Uint())), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return NilValue, nil
+		}
+		elements := make([]Value, rv.Len())
+		for idx := range elements {
+			element, err := fromGo(rv.Index(idx))
+			if err != nil {
+				return NilValue, err
+			}
+			elements[idx] = element
+		}
+		return ObjectValue(NewList(elements...)), nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return NilValue, nil
+		}
+		return fromGoMap(rv)
+	case reflect.Interface, reflect.Pointer:
+		if rv.IsNil() {
+			return NilValue, nil
+		}
+		if rv.Kind() == reflect.Interface {
+			return fromGo(rv.Elem())
+		}
+	}
+	return NilValue, errors.New("cannot convert " + rv.Type().String() + " to a lox value")
+}
+
+// isRuntimeObject reports whether obj is one of the lox objects that ToGo
+// hands out as they are, so they can come back into lox unchanged.
+func isRuntimeObject(obj any) bool {
+	switch obj.(type) {
+	case LoxFunction, *LoxClass, *LoxInstance, *LoxList, *LoxMap, *LoxRange, *NativeFunction,
+		*vmClosure, *vmClass, *vmInstance, *vmBoundMethod:
+		return true
+	}
+	return false
+}
+
+// fromGoMap converts a go map into a lox map. Go maps are unordered, so the
+// entries are sorted by key to keep the lox map deterministic.
+func fromGoMap(rv reflect.Value) (Value, error) {
+	entries := make([][2]Value, 0, rv.Len())
+	for it := rv.MapRange(); it.Next(); {
+		key, err := fromGo(it.Key())
+		if err != nil {
+			return NilValue, err
+		}
+		value, err := fromGo(it.Value())
+		if err != nil {
+			return NilValue, err
+		}
+		entries = append(entries, [2]Value{key, value})
+	}
+	slices.SortFunc(entries, func(a, b [2]Value) int {
+		if a[0].IsNumber() && b[0].IsNumber() {
+			return cmp.Compare(a[0].AsNumber(), b[0].AsNumber())
+		}
+		return cmp.Compare(a[0].String(), b[0].String())
+	})
+	m := NewMap()
+	for _, entry := range entries {
+		if err := m.Set(entry[0], entry[1]); err != nil {
+			return NilValue, err
+		}
+	}
+	return ObjectValue(m), nil
+}
+
+// ToGo returns the go representation of v: nil, bool, int64, float64,
+// string, []any for lists, map[any]any for maps or the runtime object
+// itself.
+func (v Value) ToGo() any {
+	switch v.Type {
+	case NIL_VALUE:
+		return nil
+	case BOOL_VALUE:
+		return v.AsBool()
+	case NUMBER_VALUE:
+		return v.AsNumber()
+	case INT_VALUE:
+		return v.AsInt()
+	}
+	if list, ok := v.obj.(*LoxList); ok {
+		elements := make([]any, len(list.elements))
+		for idx, element := range list.elements {
+			elements[idx] = element.ToGo()
+		}
+		return elements
+	}
+	if m, ok := v.obj.(*LoxMap); ok {
+		entries := make(map[any]any, m.Len())
+		for idx, key := range m.keys {
+			entries[key.ToGo()] = m.values[idx].ToGo()
+		}
+		return entries
+	}
+	return v.obj
+}
+
+// FromGo converts a go value into a lox value using the same rules as the
+// results of functions registered with Define.
+func FromGo(v any) (Value, error) {
+	return fromGo(reflect.ValueOf(v))
+}
+
+var clock = &NativeFunction{"clock", 0, func(args []Value) (Value, error) {
+	return NumberValue(float64(time.Now().UnixMilli()) / 1000), nil
+}}
@@ -0,0 +1,232 @@
+package glox
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefine(t *testing.T) {
+	for _, b := range backends {
+		out := &bytes.Buffer{}
+		vm := New(WithBackend(b.backend), WithStdout(out))
+		defines := map[string]any{
+			"add":    func(a, b int) int { return a + b },
+			"repeat": strings.Repeat,
+			"half":   func(f float32) float64 { return float64(f) / 2 },
+			"not":    func(v bool) bool { return !v },
+			"sum": func(start float64, rest ...float64) float64 {
+				for _, n := range rest {
+					start += n
+				}
+				return start
+			},
+			"count": NativeFunc(func(args []Value) (Value, error) {
+				return NumberValue(float64(len(args))), nil
+			}),
+			"describe": func(v any) string {
+				if v == nil {
+					return "nothing"
+				}
+				return "something"
+			},
+			"fail": func() error { return errors.New("failed on purpose") },
+		}
+		for name, fn := range defines {
+			if err := vm.Define(name, fn); err != nil {
+				t.Fatalf("%v: Define(%q) failed: %v", b.name, name, err)
+			}
+		}
+
+		src := `print add(2, 3);
+print repeat("ab", 3);
+print half(3);
+print not(false);
+print sum(1);
+print sum(1, 2, 3);
+print count();
+print count(nil, "a", 1);
+print describe(nil);
+print describe(add);
+print add;
+`
+		if _, err := vm.Eval(src); err != nil {
+			t.Fatalf("%v: Eval failed: %v", b.name, err)
+		}
+		want := "5\nababab\n1.5\ntrue\n1\n6\n0\n3\nnothing\nsomething\n<native fn>\n"
+		if got := out.String(); got != want {
+			t.Errorf("%v: printed %q, want %q", b.name, got, want)
+		}
+
+		for _, src := range []string{
+			"fail();",
+			"add(1);",
+			"add(1, 2, 3);",
+			"add(1.5, 2);",
+			`add("1", 2);`,
+			"sum();",
+			`not(nil);`,
+		} {
+			_, err := vm.Eval(src)
+			var runtimeErr RunTimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Errorf("%v: Eval(%q) returned %v, want a RunTimeError", b.name, src, err)
+			}
+		}
+	}
+}
+
+func TestDefineRejectsUnsupportedTypes(t *testing.T) {
+	for _, fn := range []any{
+		42,
+		func(ch chan int) {},
+		func() (int, int) { return 0, 0 },
+		func() map[int]func() { return nil },
+	} {
+		if err := New().Define("f", fn); err == nil {
+			t.Errorf("Define(%T) succeeded, want an error", fn)
+		}
+	}
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		in   any
+		want Value
+	}{
+		{nil, NilValue},
+		{true, BoolValue(true)},
+		{"s", ObjectValue("s")},
+		{int8(-3), NumberValue(-3)},
+		{uint(7), NumberValue(7)},
+		{float32(0.5), NumberValue(0.5)},
+		{(*int)(nil), NilValue},
+		{(*LoxInstance)(nil), NilValue},
+	}
+	for _, test := range tests {
+		got, err := FromGo(test.in)
+		if err != nil {
+			t.Errorf("FromGo(%#v) failed: %v", test.in, err)
+			continue
+		}
+		if !got.Equals(test.want) {
+			t.Errorf("FromGo(%#v) = %v, want %v", test.in, got, test.want)
+		}
+	}
+	if _, err := FromGo(struct{}{}); err == nil {
+		t.Error("FromGo(struct{}{}) succeeded, want an error")
+	}
+}
+
+func TestDefineSlices(t *testing.T) {
+	for _, b := range backends {
+		out := &bytes.Buffer{}
+		vm := New(WithBackend(b.backend), WithStdout(out))
+		vm.Define("total", func(xs []float64) float64 {
+			sum := 0.0
+			for _, x := range xs {
+				sum += x
+			}
+			return sum
+		})
+		vm.Define("split", strings.Fields)
+		vm.Define("echo", func(v any) any { return v })
+
+		if _, err := vm.Eval(`print total([1, 2.5]); print split(" a b "); print echo([1, ["x"], nil]);`); err != nil {
+			t.Fatalf("%v: Eval failed: %v", b.name, err)
+		}
+		if want := "3.5\n[\"a\", \"b\"]\n[1, [\"x\"], nil]\n"; out.String() != want {
+			t.Errorf("%v: printed %q, want %q", b.name, out.String(), want)
+		}
+		if _, err := vm.Eval(`total([1, "2"]);`); err == nil {
+			t.Errorf("%v: converting a list with a string to []float64 succeeded", b.name)
+		}
+	}
+}
+
+func TestDefineMaps(t *testing.T) {
+	for _, b := range backends {
+		out := &bytes.Buffer{}
+		vm := New(WithBackend(b.backend), WithStdout(out))
+		vm.Define("counts", func(words []string) map[string]int {
+			counts := map[string]int{}
+			for _, word := range words {
+				counts[word]++
+			}
+			return counts
+		})
+		vm.Define("get", func(m map[string]float64, key string) float64 { return m[key] })
+
+		if _, err := vm.Eval(`print counts(["b", "a", "b"]); print get({"x": 1.5}, "x");`); err != nil {
+			t.Fatalf("%v: Eval failed: %v", b.name, err)
+		}
+		if want := "{\"a\": 1, \"b\": 2}\n1.5\n"; out.String() != want {
+			t.Errorf("%v: printed %q, want %q", b.name, out.String(), want)
+		}
+		if _, err := vm.Eval(`get({1: 1}, "x");`); err == nil {
+			t.Errorf("%v: converting a map with a number key to map[string]float64 succeeded", b.name)
+		}
+	}
+}
+
+func TestNativeKeepsArguments(t *testing.T) {
+	for _, b := range backends {
+		out := &bytes.Buffer{}
+		vm := New(WithBackend(b.backend), WithStdout(out))
+		var kept []Value
+		vm.Define("keep", NativeFunc(func(args []Value) (Value, error) {
+			kept = args
+			return NilValue, nil
+		}))
+		if _, err := vm.Eval(`keep("a", "b"); var x = 1; var y = 2; print x + y;`); err != nil {
+			t.Fatalf("%v: Eval failed: %v", b.name, err)
+		}
+		if len(kept) != 2 || kept[0].AsString() != "a" || kept[1].AsString() != "b" {
+			t.Errorf("%v: kept arguments changed to %v", b.name, kept)
+		}
+	}
+}
+
+func TestNativeIntegerRange(t *testing.T) {
+	vm := New(WithStdout(&bytes.Buffer{}))
+	vm.Define("byte", func(b uint8) uint8 { return b })
+	vm.Define("small", func(n int8) int8 { return n })
+	vm.Define("big", func(n int64) int64 { return n })
+
+	for _, src := range []string{"byte(255);", "byte(0);", "small(-128);", "small(127.0);", "big(9223372036854775807);", "big(-1e18);"} {
+		if _, err := vm.Eval(src); err != nil {
+			t.Errorf("Eval(%q) failed: %v", src, err)
+		}
+	}
+	for _, src := range []string{"byte(256);", "byte(-1);", "small(128);", "small(-129.0);", "big(1e19);", "big(-1e19);", "byte(1.5);"} {
+		if _, err := vm.Eval(src); err == nil {
+			t.Errorf("Eval(%q) succeeded, want an out of range error", src)
+		}
+	}
+}
+
+func TestDefineRoundTripsRuntimeObjects(t *testing.T) {
+	for _, b := range backends {
+		out := &bytes.Buffer{}
+		vm := New(WithBackend(b.backend), WithStdout(out))
+		vm.Define("id", func(v any) any { return v })
+
+		src := `class C { get() { return "method"; } }
+var c = C();
+print id(c) == c;
+print id(C) == C;
+print id(c.get)();
+print id(fun () { return "function"; })();
+print id(clock) == clock;
+print id(range(2, 4));
+print id([c]);`
+		if _, err := vm.Eval(src); err != nil {
+			t.Fatalf("%v: Eval failed: %v", b.name, err)
+		}
+		want := "true\ntrue\nmethod\nfunction\ntrue\nrange(2, 4, 1)\n[<C instance>]\n"
+		if got := out.String(); got != want {
+			t.Errorf("%v: printed %q, want %q", b.name, got, want)
+		}
+	}
+}
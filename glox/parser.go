@@ -2,6 +2,7 @@ package glox
 
 import (
 	"fmt"
+	"sort"
 )
 
 type parser struct {
@@ -9,22 +10,45 @@ type parser struct {
 	pos         int
 	errors      []error
 	syncronized bool
+	// loops counts the loops around the statement being parsed in the
+	// current function, break and continue are only allowed inside one
+	loops int
+	// trailingExpr lets the last expression statement leave out its
+	// semicolon so that embedders can evaluate plain expressions, topLevel
+	// is where the top level declaration being parsed starts
+	trailingExpr bool
+	topLevel     int
 }
 
 func ParseCode(code string) (stmts []Stmt, errs []error) {
-	tokens, err := Scan(code)
-	if err != nil {
-		errs = append(errs, err)
-		return
-	}
+	return ParseFile("", code)
+}
+
+// ParseFile scans and parses code read from file, it returns every lexical
+// and syntax error ordered by position.
+func ParseFile(file, code string) (stmts []Stmt, errs []error) {
+	return parseFile(file, code, false)
+}
 
-	return Parse(tokens)
+func parseFile(file, code string, trailingExpr bool) (stmts []Stmt, errs []error) {
+	tokens, errs := ScanFile(file, code)
+	stmts, parseErrs := parse(tokens, trailingExpr)
+	errs = append(errs, parseErrs...)
+	sort.SliceStable(errs, func(a, b int) bool {
+		return errs[a].(ParseError).Token.Start < errs[b].(ParseError).Token.Start
+	})
+	return stmts, errs
 }
 
 func Parse(tokens []Token) ([]Stmt, []error) {
-	p := parser{tokens: tokens, pos: 0, syncronized: true}
+	return parse(tokens, false)
+}
+
+func parse(tokens []Token, trailingExpr bool) ([]Stmt, []error) {
+	p := parser{tokens: tokens, pos: 0, syncronized: true, trailingExpr: trailingExpr}
 	stmts := []Stmt{}
 	for !p.isAtEnd() {
+		p.topLevel = p.pos
 		stmt := p.decleration()
 		if stmt != nil {
 			stmts = append(stmts, stmt)
@@ -34,8 +58,12 @@ func Parse(tokens []Token) ([]Stmt, []error) {
 }
 
 func (p *parser) decleration() Stmt {
+	start := p.pos
 	var ret Stmt
-	if p.match(FUN) {
+	if p.match(CLASS) {
+		ret = p.classDecl()
+	} else if p.check(FUN) && p.peek(1).Type == IDENTIFIER {
+		p.advance()
 		ret = p.function("function")
 	} else if p.match(VAR) {
 		ret = p.varDecl()
@@ -43,21 +71,51 @@ func (p *parser) decleration() Stmt {
 		ret = p.statement()
 	}
 	if !p.syncronized {
-		p.syncronize()
+		p.syncronize(start)
 		return nil
 	}
 	return ret
 }
 
+func (p *parser) classDecl() Stmt {
+	name := p.consume(IDENTIFIER, "Expect class name")
+
+	var superclass *VariableExpr
+	if p.match(LESS) {
+		p.consume(IDENTIFIER, "Expect superclass name")
+		superclass = &VariableExpr{p.peek(-1)}
+	}
+
+	p.consume(LEFT_BRACE, "Expect '{' before class body")
+
+	methods := []*Function{}
+	for !p.check(RIGHT_BRACE) && !p.isAtEnd() && p.syncronized {
+		if method, ok := p.function("method").(*Function); ok {
+			methods = append(methods, method)
+		}
+	}
+
+	p.consume(RIGHT_BRACE, "Expect '}' after class body")
+	return &ClassStmt{name, superclass, methods}
+}
+
 func (p *parser) function(kind string) Stmt {
-	name := p.consume(IDENTIFIER, fmt.Sprint("Expect", kind, "name"))
-	p.consume(LEFT_PAREN, fmt.Sprint("Expect '(' after", kind, "name"))
+	name := p.consume(IDENTIFIER, "Expect "+kind+" name")
+	p.consume(LEFT_PAREN, "Expect '(' after "+kind+" name")
+	params := p.parameters()
+	p.consume(LEFT_BRACE, "Expect '{' before "+kind+" body")
+	return &Function{name, params, p.functionBody()}
+}
+
+// parameters parses the parameter list of a function after its opening
+// paren, up to and including the closing paren.
+func (p *parser) parameters() []Token {
 	params := []Token{}
 	if !p.check(RIGHT_PAREN) {
 		for {
 			if len(params) >= 255 {
 				p.error(p.peek(0), "Can't have more than 255 parameters")
-				return nil
+				return params
 			}
 			params = append(params, p.consume(IDENTIFIER, "Expect parameters name."))
 			if !p.match(COMMA) {
@@ -66,9 +124,58 @@ func (p *parser) function(kind string) Stmt {
 		}
 	}
 	p.consume(RIGHT_PAREN, "Expect ')' after paramerters")
-	p.consume(LEFT_BRACE, "Expect '{' before "+kind+" body")
+	return params
+}
+
+// functionBody parses the block of a function after its opening brace,
+// break and continue inside it can't reach loops around the function.
+func (p *parser) functionBody() []Stmt {
+	loops := p.loops
+	p.loops = 0
 	body := p.block()
-	return Function{name, params, body.Stmts}
+	p.loops = loops
+	return body.Stmts
+}
+
+// lambda parses an anonymous function expression, fun (a, b) { body }, after
+// its keyword. Anonymous functions are named lambda in traces.
+func (p *parser) lambda() *FunctionExpr {
+	name := p.peek(-1)
+	name.Lexme = "lambda"
+	p.consume(LEFT_PAREN, "Expect '(' after 'fun'")
+	params := p.parameters()
+	p.consume(LEFT_BRACE, "Expect '{' before function body")
+	return &FunctionExpr{&Function{name, params, p.functionBody()}}
+}
+
+// arrow parses an arrow lambda, (a, b) => expr, after its opening paren. The
+// body is a single expression that the lambda returns.
+func (p *parser) arrow() *FunctionExpr {
+	name := p.peek(-1)
+	name.Lexme = "lambda"
+	params := p.parameters()
+	arrow := p.consume(ARROW, "Expect '=>' after lambda parameters")
+	body := p.expression()
+	return &FunctionExpr{&Function{name, params, []Stmt{&ReturnStmt{arrow, body}}}}
+}
+
+// arrowAhead reports whether the tokens after an opening paren are the
+// parameters of an arrow lambda rather than a grouping.
+func (p *parser) arrowAhead() bool {
+	idx := p.pos
+	if p.tokens[idx].Type != RIGHT_PAREN {
+		for {
+			if p.tokens[idx].Type != IDENTIFIER {
+				return false
+			}
+			idx++
+			if p.tokens[idx].Type != COMMA {
+				break
+			}
+			idx++
+		}
+	}
+	return p.tokens[idx].Type == RIGHT_PAREN && p.tokens[idx+1].Type == ARROW
 }
 
 func (p *parser) varDecl() Stmt {
@@ -80,7 +187,7 @@ func (p *parser) varDecl() Stmt {
 	}
 
 	p.consume(SEMICOLON, "Expected ';' after variable expr")
-	return VarDecl{Name: name, Initializer: init}
+	return &VarDecl{Name: name, Initializer: init}
 }
 
 func (p *parser) statement() Stmt {
@@ -99,12 +206,47 @@ func (p *parser) statement() Stmt {
 	if p.match(FOR) {
 		return p.forStmt()
 	}
+	if p.match(RETURN) {
+		return p.returnStmt()
+	}
+	if p.match(BREAK) {
+		keyword := p.loopControl()
+		return &BreakStmt{keyword}
+	}
+	if p.match(CONTINUE) {
+		keyword := p.loopControl()
+		return &ContinueStmt{keyword}
+	}
 
 	return p.exprStmt()
 }
 
+func (p *parser) returnStmt() *ReturnStmt {
+	keyword := p.peek(-1)
+	var value Expr
+	if !p.check(SEMICOLON) {
+		value = p.expression()
+	}
+	p.consume(SEMICOLON, "Expect ';' after return value")
+	return &ReturnStmt{keyword, value}
+}
+
+// loopControl parses the rest of a break or continue statement and returns
+// its keyword.
+func (p *parser) loopControl() Token {
+	keyword := p.peek(-1)
+	if p.loops == 0 {
+		p.error(keyword, fmt.Sprintf("Can't use '%v' outside of a loop", keyword.Lexme))
+	}
+	p.consume(SEMICOLON, fmt.Sprintf("Expect ';' after '%v'", keyword.Lexme))
+	return keyword
+}
+
 func (p *parser) forStmt() Stmt {
 	p.consume(LEFT_PAREN, "Expect '(' after 'for'")
+	if p.check(VAR) && p.peek(1).Type == IDENTIFIER && p.peek(2).Type == IN {
+		return p.forInStmt()
+	}
 	var init Stmt
 	if p.match(SEMICOLON) {
 	} else if p.match(VAR) {
@@ -113,7 +255,7 @@ func (p *parser) forStmt() Stmt {
 		init = p.exprStmt()
 	}
 
-	var cond Expr = LiteralExpr{Value: true}
+	var cond Expr
 	if !p.check(SEMICOLON) {
 		cond = p.expression()
 	}
@@ -125,51 +267,68 @@ func (p *parser) forStmt() Stmt {
 	}
 	p.consume(RIGHT_PAREN, "Expect ')' after for clauses")
 
+	p.loops++
 	body := p.statement()
-	if incr != nil {
-		body = Block{[]Stmt{body, ExprStmt{incr}}}
-	}
-	body = WhileStmt{cond, body}
+	p.loops--
 
-	if init != nil {
-		body = Block{[]Stmt{init, body}}
-	}
+	return &ForStmt{init, cond, incr, body}
+}
 
-	return body
+// forInStmt parses the rest of for (var name in iterable) body.
+func (p *parser) forInStmt() *ForInStmt {
+	p.consume(VAR, "Expect 'var' before loop variable")
+	name := p.consume(IDENTIFIER, "Expect loop variable name")
+	in := p.consume(IN, "Expect 'in' after loop variable")
+	iterable := p.expression()
+	p.consume(RIGHT_PAREN, "Expect ')' after for-in clause")
+
+	p.loops++
+	body := p.statement()
+	p.loops--
+
+	return &ForInStmt{name, in, iterable, body}
 }
 
-func (p *parser) while() WhileStmt {
+func (p *parser) while() *WhileStmt {
 	p.consume(LEFT_PAREN, "Expect '(' after 'while'")
 	cond := p.expression()
 	p.consume(RIGHT_PAREN, "Expected ')' after while condition")
+	p.loops++
 	body := p.statement()
+	p.loops--
 
-	return WhileStmt{cond, body}
+	return &WhileStmt{cond, body}
 }
 
-func (p *parser) block() Block {
+func (p *parser) block() *Block {
 	stmts := []Stmt{}
 
 	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
 		stmts = append(stmts, p.decleration())
 	}
 	p.consume(RIGHT_BRACE, "Expect '}' after blokc")
-	return Block{stmts}
+	return &Block{stmts}
 }
 
-func (p *parser) exprStmt() ExprStmt {
+func (p *parser) exprStmt() *ExprStmt {
+	start := p.pos
 	expr := p.expression()
+	// nested statements like the body of an if always need the semicolon
+	if p.trailingExpr && start == p.topLevel && p.isAtEnd() {
+		return &ExprStmt{Expr: expr}
+	}
 	p.consume(SEMICOLON, "Expected semicolon")
-	return ExprStmt{Expr: expr}
+	return &ExprStmt{Expr: expr}
 }
 
-func (p *parser) printStmt() PrintStmt {
+func (p *parser) printStmt() *PrintStmt {
+	keyword := p.peek(-1)
 	expr := p.expression()
 	p.consume(SEMICOLON, "Expect ';' after value.")
-	return PrintStmt{Expr: expr}
+	return &PrintStmt{Keyword: keyword, Expr: expr}
 }
 
-func (p *parser) ifStmt() IfStmt {
+func (p *parser) ifStmt() *IfStmt {
 	p.consume(LEFT_PAREN, "Expect '(' after 'if'")
 	condition := p.expression()
 	p.consume(RIGHT_PAREN, "Expect ')' after if contition")
@@ -180,26 +339,62 @@ func (p *parser) ifStmt() IfStmt {
 		elseBranch = p.statement()
 	}
 
-	return IfStmt{Condition: condition, ThenBranch: then, ElseBranch: elseBranch}
+	return &IfStmt{Condition: condition, ThenBranch: then, ElseBranch: elseBranch}
 }
 
 func (p *parser) expression() Expr {
 	return p.assignment()
 }
 
+// compoundOperators maps compound assignment operators to the binary
+// operator they apply.
+var compoundOperators = map[TokenType]TokenType{
+	PLUS_EQUAL:    PLUS,
+	MINUS_EQUAL:   MINUS,
+	STAR_EQUAL:    STAR,
+	SLASH_EQUAL:   SLASH,
+	PERCENT_EQUAL: PERCENT,
+}
+
 func (p *parser) assignment() Expr {
 	expr := p.or()
 
+	if p.match(PLUS_EQUAL, MINUS_EQUAL, STAR_EQUAL, SLASH_EQUAL, PERCENT_EQUAL) {
+		compound := p.peek(-1)
+		operator := compound
+		operator.Type = compoundOperators[compound.Type]
+		value := p.assignment()
+
+		// a += b becomes a = a + b, properties get their own node so that
+		// the object is only evaluated once
+		switch target := expr.(type) {
+		case *VariableExpr:
+			return &AssignExpr{target.Name, &BinaryExpr{&VariableExpr{target.Name}, operator, value}}
+		case *GetExpr:
+			return &CompoundSetExpr{target.Object, target.Name, operator, value}
+		case *IndexExpr:
+			return &CompoundIndexSetExpr{target.Object, target.Bracket, target.Index, operator, value}
+		default:
+			p.error(compound, "Invalid assignment target")
+			return nil
+		}
+	}
+
 	if p.match(EQUAL) {
 		equals := p.peek(-1)
 		value := p.assignment()
 
-		varr, ok := expr.(VariableExpr)
-		if !ok {
+		switch target := expr.(type) {
+		case *VariableExpr:
+			return &AssignExpr{target.Name, value}
+		case *GetExpr:
+			return &SetExpr{target.Object, target.Name, value}
+		case *IndexExpr:
+			return &IndexSetExpr{target.Object, target.Bracket, target.Index, value}
+		default:
 			p.error(equals, "Invalid assignment target")
 			return nil
 		}
-		return AssignExpr{varr.Name, value}
 	}
 
 	return expr
@@ -210,7 +405,7 @@ func (p *parser) or() Expr {
 	for p.match(OR) {
 		op := p.peek(-1)
 		right := p.and()
-		expr = LogicalExpr{expr, op, right}
+		expr = &LogicalExpr{expr, op, right}
 	}
 
 	return expr
@@ -221,7 +416,7 @@ func (p *parser) and() Expr {
 	for p.match(AND) {
 		op := p.peek(-1)
 		right := p.equality()
-		expr = LogicalExpr{expr, op, right}
+		expr = &LogicalExpr{expr, op, right}
 	}
 
 	return expr
@@ -232,17 +427,57 @@ func (p *parser) equality() Expr {
 	for p.match(BANG_EQUAL, EQUAL_EQUAL) {
 		operator := p.peek(-1)
 		right := p.comparison()
-		expr = BinaryExpr{Left: expr, Operator: operator, Right: right}
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
 	}
 	return expr
 }
 
 func (p *parser) comparison() Expr {
-	expr := p.term()
+	expr := p.bitOr()
 	for p.match(GREATER, GREATER_EQUAL, LESS, LESS_EQUAL) {
+		operator := p.peek(-1)
+		right := p.bitOr()
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+	return expr
+}
+
+func (p *parser) bitOr() Expr {
+	expr := p.bitXor()
+	for p.match(PIPE) {
+		operator := p.peek(-1)
+		right := p.bitXor()
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+	return expr
+}
+
+func (p *parser) bitXor() Expr {
+	expr := p.bitAnd()
+	for p.match(CARET) {
+		operator := p.peek(-1)
+		right := p.bitAnd()
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+	return expr
+}
+
+func (p *parser) bitAnd() Expr {
+	expr := p.shift()
+	for p.match(AMPERSAND) {
+		operator := p.peek(-1)
+		right := p.shift()
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+	return expr
+}
+
+func (p *parser) shift() Expr {
+	expr := p.term()
+	for p.match(LESS_LESS, GREATER_GREATER) {
 		operator := p.peek(-1)
 		right := p.term()
-		expr = BinaryExpr{Left: expr, Operator: operator, Right: right}
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
 	}
 	return expr
 }
@@ -253,7 +488,7 @@ func (p *parser) term() Expr {
 	for p.match(MINUS, PLUS) {
 		operator := p.peek(-1)
 		right := p.factor()
-		expr = BinaryExpr{Left: expr, Operator: operator, Right: right}
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
 	}
 
 	return expr
@@ -262,23 +497,35 @@ func (p *parser) term() Expr {
 func (p *parser) factor() Expr {
 	expr := p.unary()
 
-	for p.match(SLASH, STAR) {
+	for p.match(SLASH, STAR, PERCENT, TILDE_SLASH) {
 		operator := p.peek(-1)
 		right := p.unary()
-		expr = BinaryExpr{Left: expr, Operator: operator, Right: right}
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
 	}
 
 	return expr
 }
 
 func (p *parser) unary() Expr {
-	if p.match(BANG, MINUS) {
+	if p.match(BANG, MINUS, TILDE) {
 		operator := p.peek(-1)
 		right := p.unary()
-		return UnaryExpr{Operator: operator, Expr: right}
+		return &UnaryExpr{Operator: operator, Expr: right}
 	}
 
-	return p.call()
+	return p.power()
+}
+
+// power binds tighter than a unary operator on its left, -2 ** 2 is -4, and
+// is right associative.
+func (p *parser) power() Expr {
+	expr := p.call()
+	if p.match(STAR_STAR) {
+		operator := p.peek(-1)
+		right := p.unary()
+		expr = &BinaryExpr{Left: expr, Operator: operator, Right: right}
+	}
+	return expr
 }
 
 func (p *parser) call() Expr {
@@ -286,6 +533,14 @@ func (p *parser) call() Expr {
 	for {
 		if p.match(LEFT_PAREN) {
 			expr = p.finishCall(expr)
+		} else if p.match(DOT) {
+			name := p.consume(IDENTIFIER, "Expect property name after '.'")
+			expr = &GetExpr{expr, name}
+		} else if p.match(LEFT_BRACKET) {
+			bracket := p.peek(-1)
+			index := p.expression()
+			p.consume(RIGHT_BRACKET, "Expect ']' after index")
+			expr = &IndexExpr{expr, bracket, index}
 		} else {
 			break
 		}
@@ -309,39 +564,127 @@ func (p *parser) finishCall(expr Expr) Expr {
 	}
 
 	paren := p.consume(RIGHT_PAREN, "Expect ')' after arguments")
-	return CallExpr{expr, paren, args}
+	return &CallExpr{expr, paren, args}
+}
+
+// list parses the elements of a list literal after its opening bracket, a
+// trailing comma is allowed.
+func (p *parser) list() Expr {
+	bracket := p.peek(-1)
+	elements := []Expr{}
+	for !p.check(RIGHT_BRACKET) && !p.isAtEnd() {
+		elements = append(elements, p.expression())
+		if !p.match(COMMA) {
+			break
+		}
+	}
+	p.consume(RIGHT_BRACKET, "Expect ']' after list elements")
+	return &ListExpr{bracket, elements}
+}
+
+// mapLiteral parses the entries of a map literal after its opening brace, a
+// trailing comma is allowed. A '{' that starts a statement is a block, so a
+// map literal used as a statement has to be parenthesized.
+func (p *parser) mapLiteral() Expr {
+	brace := p.peek(-1)
+	keys, values := []Expr{}, []Expr{}
+	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		keys = append(keys, p.expression())
+		p.consume(COLON, "Expect ':' after map key")
+		values = append(values, p.expression())
+		if !p.match(COMMA) {
+			break
+		}
+	}
+	p.consume(RIGHT_BRACE, "Expect '}' after map entries")
+	return &MapExpr{brace, keys, values}
 }
 
 func (p *parser) primary() Expr {
 
 	if p.match(FALSE) {
-		return LiteralExpr{false}
+		return &LiteralExpr{false, p.peek(-1)}
 	}
 	if p.match(TRUE) {
-		return LiteralExpr{true}
+		return &LiteralExpr{true, p.peek(-1)}
 	}
 	if p.match(NIL) {
-		return LiteralExpr{nil}
+		return &LiteralExpr{nil, p.peek(-1)}
 	}
 
 	if p.match(NUMBER, STRING) {
-		return LiteralExpr{p.peek(-1).Literal}
+		return &LiteralExpr{p.peek(-1).Literal, p.peek(-1)}
+	}
+
+	if p.match(INTERPOLATION) {
+		return p.interpolation()
+	}
+
+	if p.match(SUPER) {
+		keyword := p.peek(-1)
+		p.consume(DOT, "Expect '.' after 'super'")
+		method := p.consume(IDENTIFIER, "Expect superclass method name")
+		return &SuperExpr{keyword, method}
+	}
+
+	if p.match(THIS) {
+		return &ThisExpr{p.peek(-1)}
 	}
 
 	if p.match(IDENTIFIER) {
-		return VariableExpr{p.peek(-1)}
+		return &VariableExpr{p.peek(-1)}
+	}
+
+	if p.match(LEFT_BRACKET) {
+		return p.list()
+	}
+
+	if p.match(LEFT_BRACE) {
+		return p.mapLiteral()
+	}
+
+	if p.match(FUN) {
+		return p.lambda()
 	}
 
 	if p.match(LEFT_PAREN) {
+		if p.arrowAhead() {
+			return p.arrow()
+		}
 		expr := p.expression()
 		p.consume(RIGHT_PAREN, "Expected ')' after expression.")
-		return GroupingExpr{Expr: expr}
+		return &GroupingExpr{Expr: expr}
 	}
 
 	p.error(p.peek(0), "Expected Expression")
 	return nil
 }
 
+// interpolation lowers "a ${b} c" to "a " + StringifyExpr(b) + " c", the
+// INTERPOLATION token holding "a " was just consumed. Each StringifyExpr
+// keeps the part before it to report errors of toString methods.
+func (p *parser) interpolation() Expr {
+	part := p.peek(-1)
+	var expr Expr = &LiteralExpr{part.Literal, part}
+	for {
+		plus := part
+		plus.Type = PLUS
+		expr = &BinaryExpr{expr, plus, &StringifyExpr{part, p.expression()}}
+
+		if p.match(INTERPOLATION_MID) {
+			part = p.peek(-1)
+		} else {
+			part = p.consume(INTERPOLATION_END, "Expect '}' after interpolated expression")
+		}
+		plus = part
+		plus.Type = PLUS
+		expr = &BinaryExpr{expr, plus, &LiteralExpr{part.Literal, part}}
+		if part.Type != INTERPOLATION_MID {
+			return expr
+		}
+	}
+}
+
 func (p *parser) consume(t TokenType, message string) Token {
 	if p.check(t) {
 		return p.advance()
@@ -351,26 +694,52 @@ func (p *parser) consume(t TokenType, message string) Token {
 	return Token{}
 }
 
+// ParseError is a static error found while scanning, parsing, resolving or
+// compiling a program, before any of it runs. Code names the pass that found
+// it.
+type ParseError struct {
+	Token   Token
+	Message string
+	Code    string
+}
+
+func (e ParseError) Error() string {
+	if e.Token.Lexme == "" {
+		return fmt.Sprintf("Error at line %v: %s", e.Token.Line, e.Message)
+	}
+	return fmt.Sprintf("Error at line %v around %v: %s", e.Token.Line, e.Token.Lexme, e.Message)
+}
+
+// error records a syntax error unless the parser is already recovering from
+// one in the same statement. ILLEGAL tokens were reported by the scanner and
+// only make the parser recover.
 func (p *parser) error(t Token, message string) {
-	p.errors = append(p.errors, fmt.Errorf("Error at line %v around %v: %s", t.Line, t.Lexme, message))
+	if p.syncronized && t.Type != ILLEGAL {
+		p.errors = append(p.errors, ParseError{t, message, "syntax"})
+	}
 	p.syncronized = false
 }
 
-func (p *parser) syncronize() {
-	p.advance()
+// syncronize skips to the start of the next statement after an error in the
+// declaration that started at start, skipping at least one token.
+func (p *parser) syncronize(start int) {
+	p.syncronized = true
+	if p.pos == start {
+		p.advance()
+	}
 	for !p.isAtEnd() {
 		if p.peek(-1).Type == SEMICOLON {
 			return
 		}
 
 		t := p.peek(0).Type
-		if t == CLASS || t == FOR || t == FUN || t == IF || t == PRINT || t == RETURN || t == VAR || t == WHILE {
+		if t == CLASS || t == FOR || t == FUN || t == IF || t == PRINT || t == RETURN || t == VAR || t == WHILE ||
+			t == BREAK || t == CONTINUE {
 			return
 		}
 
 		p.advance()
 	}
-	p.syncronized = true
 }
 
 // Utils
@@ -0,0 +1,400 @@
+package glox
+
+type functionType int
+
+const (
+	NONE_FUNCTION functionType = iota
+	FUNCTION
+	METHOD
+	INITIALIZER
+)
+
+type classType int
+
+const (
+	NONE_CLASS classType = iota
+	CLASS_BODY
+	SUBCLASS_BODY
+)
+
+type Resolver struct {
+	interpreter     *Interpreter
+	scopes          []map[string]bool
+	currentFunction functionType
+	currentClass    classType
+	errors          []error
+}
+
+// Resolve reports static errors in stmts and records the scope depth of every
+// local variable access in i. i may be nil when only the errors are needed.
+func Resolve(i *Interpreter, stmts []Stmt) []error {
+	r := Resolver{interpreter: i}
+	r.resolveStmts(stmts)
+	return r.errors
+}
+
+// VisitBinaryExpr implements ExprVisitor.
+func (r *Resolver) VisitBinaryExpr(expr *BinaryExpr) (Value, error) {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return NilValue, nil
+}
+
+// VisitGroupingExpr implements ExprVisitor.
+func (r *Resolver) VisitGroupingExpr(expr *GroupingExpr) (Value, error) {
+	r.resolveExpr(expr.Expr)
+	return NilValue, nil
+}
+
+// VisitLiteralExpr implements ExprVisitor.
+func (r *Resolver) VisitLiteralExpr(expr *LiteralExpr) (Value, error) {
+	return NilValue, nil
+}
+
+// VisitUnaryExpr implements ExprVisitor.
+func (r *Resolver) VisitUnaryExpr(expr *UnaryExpr) (Value, error) {
+	r.resolveExpr(expr.Expr)
+	return NilValue, nil
+}
+
+// VisitVariableExpr implements ExprVisitor.
+func (r *Resolver) VisitVariableExpr(expr *VariableExpr) (Value, error) {
+	if len(r.scopes) != 0 {
+		defined, declared := r.scopes[len(r.scopes)-1][expr.Name.Lexme]
+		if declared && !defined {
+			r.error(expr.Name, "Can't read local variable in its own initializer")
+		}
+	}
+	r.resolveLocal(expr, expr.Name)
+	return NilValue, nil
+}
+
+// VisitAssignExpr implements ExprVisitor.
+func (r *Resolver) VisitAssignExpr(expr *AssignExpr) (Value, error) {
+	r.resolveExpr(expr.Value)
+	r.resolveLocal(expr, expr.Name)
+	return NilValue, nil
+}
+
+// VisitLogicalExpr implements ExprVisitor.
+func (r *Resolver) VisitLogicalExpr(expr *LogicalExpr) (Value, error) {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return NilValue, nil
+}
+
+// VisitCallExpr implements ExprVisitor.
+func (r *Resolver) VisitCallExpr(expr *CallExpr) (Value, error) {
+	r.resolveExpr(expr.Callee)
+	for _, arg := range expr.Arguments {
+		r.resolveExpr(arg)
+	}
+	return NilValue, nil
+}
+
+// VisitGetExpr implements ExprVisitor.
+func (r *Resolver) VisitGetExpr(expr *GetExpr) (Value, error) {
+	r.resolveExpr(expr.Object)
+	return NilValue, nil
+}
+
+// VisitSetExpr implements ExprVisitor.
+func (r *Resolver) VisitSetExpr(expr *SetExpr) (Value, error) {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	return NilValue, nil
+}
+
+// VisitCompoundSetExpr implements ExprVisitor.
+func (r *Resolver) VisitCompoundSetExpr(expr *CompoundSetExpr) (Value, error) {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	return NilValue, nil
+}
+
+// VisitFunctionExpr implements ExprVisitor.
+func (r *Resolver) VisitFunctionExpr(expr *FunctionExpr) (Value, error) {
+	r.resolveFunction(expr.Function, FUNCTION)
+	return NilValue, nil
+}
+
+// VisitListExpr implements ExprVisitor.
+func (r *Resolver) VisitListExpr(expr *ListExpr) (Value, error) {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return NilValue, nil
+}
+
+// VisitMapExpr implements ExprVisitor.
+func (r *Resolver) VisitMapExpr(expr *MapExpr) (Value, error) {
+	for idx := range expr.Keys {
+		r.resolveExpr(expr.Keys[idx])
+		r.resolveExpr(expr.Values[idx])
+	}
+	return NilValue, nil
+}
+
+// VisitIndexExpr implements ExprVisitor.
+func (r *Resolver) VisitIndexExpr(expr *IndexExpr) (Value, error) {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return NilValue, nil
+}
+
+// VisitIndexSetExpr implements ExprVisitor.
+func (r *Resolver) VisitIndexSetExpr(expr *IndexSetExpr) (Value, error) {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return NilValue, nil
+}
+
+// VisitCompoundIndexSetExpr implements ExprVisitor.
+func (r *Resolver) VisitCompoundIndexSetExpr(expr *CompoundIndexSetExpr) (Value, error) {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return NilValue, nil
+}
+
+// VisitThisExpr implements ExprVisitor.
+func (r *Resolver) VisitThisExpr(expr *ThisExpr) (Value, error) {
+	if r.currentClass == NONE_CLASS {
+		r.error(expr.Keyword, "Can't use 'this' outside of a class")
+		return NilValue, nil
+	}
+	r.resolveLocal(expr, expr.Keyword)
+	return NilValue, nil
+}
+
+// VisitSuperExpr implements ExprVisitor.
+func (r *Resolver) VisitSuperExpr(expr *SuperExpr) (Value, error) {
+	if r.currentClass == NONE_CLASS {
+		r.error(expr.Keyword, "Can't use 'super' outside of a class")
+	} else if r.currentClass != SUBCLASS_BODY {
+		r.error(expr.Keyword, "Can't use 'super' in a class with no superclass")
+	}
+	r.resolveLocal(expr, expr.Keyword)
+	return NilValue, nil
+}
+
+// VisitStringifyExpr implements ExprVisitor.
+func (r *Resolver) VisitStringifyExpr(expr *StringifyExpr) (Value, error) {
+	r.resolveExpr(expr.Expr)
+	return NilValue, nil
+}
+
+// VisitExprStmt implements StmtVisitor.
+func (r *Resolver) VisitExprStmt(expr *ExprStmt) (any, error) {
+	r.resolveExpr(expr.Expr)
+	return nil, nil
+}
+
+// VisitPrintStmt implements StmtVisitor.
+func (r *Resolver) VisitPrintStmt(expr *PrintStmt) (any, error) {
+	r.resolveExpr(expr.Expr)
+	return nil, nil
+}
+
+// VisitVarDecl implements StmtVisitor.
+func (r *Resolver) VisitVarDecl(expr *VarDecl) (any, error) {
+	r.declare(expr.Name)
+	if expr.Initializer != nil {
+		r.resolveExpr(expr.Initializer)
+	}
+	r.define(expr.Name)
+	return nil, nil
+}
+
+// VisitBlock implements StmtVisitor.
+func (r *Resolver) VisitBlock(expr *Block) (any, error) {
+	r.beginScope()
+	r.resolveStmts(expr.Stmts)
+	r.endScope()
+	return nil, nil
+}
+
+// VisitIfStmt implements StmtVisitor.
+func (r *Resolver) VisitIfStmt(expr *IfStmt) (any, error) {
+	r.resolveExpr(expr.Condition)
+	r.resolveStmt(expr.ThenBranch)
+	r.resolveStmt(expr.ElseBranch)
+	return nil, nil
+}
+
+// VisitWhileStmt implements StmtVisitor.
+func (r *Resolver) VisitWhileStmt(expr *WhileStmt) (any, error) {
+	r.resolveExpr(expr.Condition)
+	r.resolveStmt(expr.Body)
+	return nil, nil
+}
+
+// VisitForStmt implements StmtVisitor.
+func (r *Resolver) VisitForStmt(expr *ForStmt) (any, error) {
+	r.beginScope()
+	r.resolveStmt(expr.Initializer)
+	if expr.Condition != nil {
+		r.resolveExpr(expr.Condition)
+	}
+	if expr.Increment != nil {
+		r.resolveExpr(expr.Increment)
+	}
+	r.resolveStmt(expr.Body)
+	r.endScope()
+	return nil, nil
+}
+
+// VisitForInStmt implements StmtVisitor.
+func (r *Resolver) VisitForInStmt(expr *ForInStmt) (any, error) {
+	r.resolveExpr(expr.Iterable)
+	r.beginScope()
+	r.declare(expr.Name)
+	r.define(expr.Name)
+	r.resolveStmt(expr.Body)
+	r.endScope()
+	return nil, nil
+}
+
+// VisitBreakStmt implements StmtVisitor.
+func (r *Resolver) VisitBreakStmt(expr *BreakStmt) (any, error) {
+	return nil, nil
+}
+
+// VisitContinueStmt implements StmtVisitor.
+func (r *Resolver) VisitContinueStmt(expr *ContinueStmt) (any, error) {
+	return nil, nil
+}
+
+// VisitFunction implements StmtVisitor.
+func (r *Resolver) VisitFunction(expr *Function) (any, error) {
+	r.declare(expr.Name)
+	r.define(expr.Name)
+	r.resolveFunction(expr, FUNCTION)
+	return nil, nil
+}
+
+// VisitReturnStmt implements StmtVisitor.
+func (r *Resolver) VisitReturnStmt(expr *ReturnStmt) (any, error) {
+	if r.currentFunction == NONE_FUNCTION {
+		r.error(expr.Keyword, "Can't return from top-level code")
+	}
+	if expr.Value != nil {
+		if r.currentFunction == INITIALIZER {
+			r.error(expr.Keyword, "Can't return a value from an initializer")
+		}
+		r.resolveExpr(expr.Value)
+	}
+	return nil, nil
+}
+
+// VisitClassStmt implements StmtVisitor.
+func (r *Resolver) VisitClassStmt(expr *ClassStmt) (any, error) {
+	enclosingClass := r.currentClass
+	r.currentClass = CLASS_BODY
+	r.declare(expr.Name)
+	r.define(expr.Name)
+
+	if expr.Superclass != nil {
+		if expr.Superclass.Name.Lexme == expr.Name.Lexme {
+			r.error(expr.Superclass.Name, "A class can't inherit from itself")
+		}
+		r.currentClass = SUBCLASS_BODY
+		r.resolveExpr(expr.Superclass)
+
+		r.beginScope()
+		r.scopes[len(r.scopes)-1]["super"] = true
+	}
+
+	r.beginScope()
+	r.scopes[len(r.scopes)-1]["this"] = true
+	for _, method := range expr.Methods {
+		kind := METHOD
+		if method.Name.Lexme == "init" {
+			kind = INITIALIZER
+		}
+		r.resolveFunction(method, kind)
+	}
+	r.endScope()
+
+	if expr.Superclass != nil {
+		r.endScope()
+	}
+
+	r.currentClass = enclosingClass
+	return nil, nil
+}
+
+func (r *Resolver) resolveFunction(function *Function, kind functionType) {
+	enclosing := r.currentFunction
+	r.currentFunction = kind
+	r.beginScope()
+	for _, param := range function.Params {
+		r.declare(param)
+		r.define(param)
+	}
+	r.resolveStmts(function.Body)
+	r.endScope()
+	r.currentFunction = enclosing
+}
+
+func (r *Resolver) resolveLocal(expr Expr, name Token) {
+	for idx := len(r.scopes) - 1; idx >= 0; idx-- {
+		if _, ok := r.scopes[idx][name.Lexme]; ok {
+			if r.interpreter != nil {
+				r.interpreter.resolve(expr, len(r.scopes)-1-idx)
+			}
+			return
+		}
+	}
+}
+
+func (r *Resolver) declare(name Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[name.Lexme]; ok {
+		r.error(name, "Already a variable with this name in this scope")
+	}
+	scope[name.Lexme] = false
+}
+
+func (r *Resolver) define(name Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.Lexme] = true
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, map[string]bool{})
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *Resolver) resolveStmts(stmts []Stmt) {
+	for _, stmt := range stmts {
+		r.resolveStmt(stmt)
+	}
+}
+
+func (r *Resolver) resolveStmt(stmt Stmt) {
+	if stmt == nil {
+		return
+	}
+	stmt.Accept(r)
+}
+
+func (r *Resolver) resolveExpr(expr Expr) {
+	if expr == nil {
+		return
+	}
+	expr.Accept(r)
+}
+
+func (r *Resolver) error(t Token, message string) {
+	r.errors = append(r.errors, ParseError{t, message, "resolve"})
+}
@@ -2,98 +2,159 @@ package glox
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type TokenType int32
 
 const (
-	LEFT_PAREN    TokenType = iota + 1 // (
-	RIGHT_PAREN                        // )
-	LEFT_BRACE                         // {
-	RIGHT_BRACE                        // }
-	COMMA                              // ,
-	DOT                                // .
-	MINUS                              // -
-	PLUS                               // +
-	SEMICOLON                          // ;
-	SLASH                              // /
-	STAR                               // *
-	BANG                               // !
-	BANG_EQUAL                         // !=
-	EQUAL                              // =
-	EQUAL_EQUAL                        // ==
-	GREATER                            // >
-	GREATER_EQUAL                      // >=
-	LESS                               // <
-	LESS_EQUAL                         // <=
-	IDENTIFIER                         // [a-zA-Z][a-zA-Z0-9]*
-	STRING                             // "(.*)"
-	NUMBER                             // (\d+|\d\.\d)
-	AND                                // and
-	CLASS                              // class
-	ELSE                               // else
-	FALSE                              // false
-	FUN                                // fun
-	FOR                                // for
-	IF                                 // if
-	NIL                                // nil
-	OR                                 // or
-	PRINT                              // print
-	RETURN                             // return
-	SUPER                              // super
-	THIS                               // this
-	TRUE                               // true
-	VAR                                // var
-	WHILE                              // while
+	LEFT_PAREN        TokenType = iota + 1 // (
+	RIGHT_PAREN                            // )
+	LEFT_BRACE                             // {
+	RIGHT_BRACE                            // }
+	LEFT_BRACKET                           // [
+	RIGHT_BRACKET                          // ]
+	COMMA                                  // ,
+	COLON                                  // :
+	DOT                                    // .
+	MINUS                                  // -
+	MINUS_EQUAL                            // -=
+	PLUS                                   // +
+	PLUS_EQUAL                             // +=
+	SEMICOLON                              // ;
+	SLASH                                  // /
+	SLASH_EQUAL                            // /=
+	STAR                                   // *
+	STAR_EQUAL                             // *=
+	STAR_STAR                              // **
+	PERCENT                                // %
+	PERCENT_EQUAL                          // %=
+	TILDE_SLASH                            // ~/
+	TILDE                                  // ~
+	AMPERSAND                              // &
+	PIPE                                   // |
+	CARET                                  // ^
+	LESS_LESS                              // <<
+	GREATER_GREATER                        // >>
+	BANG                                   // !
+	BANG_EQUAL                             // !=
+	EQUAL                                  // =
+	EQUAL_EQUAL                            // ==
+	ARROW                                  // =>
+	GREATER                                // >
+	GREATER_EQUAL                          // >=
+	LESS                                   // <
+	LESS_EQUAL                             // <=
+	IDENTIFIER                             // [\p{L}_][\p{L}\p{Nd}_]*
+	STRING                                 // "(.*)"
+	INTERPOLATION                          // "(.*)${
+	INTERPOLATION_MID                      // }(.*)${
+	INTERPOLATION_END                      // }(.*)"
+	NUMBER                                 // 0x[0-9a-f_]+|0b[01_]+|[0-9_]+(\.[0-9_]+)?(e[+-]?[0-9_]+)?
+	AND                                    // and
+	BREAK                                  // break
+	CLASS                                  // class
+	CONTINUE                               // continue
+	ELSE                                   // else
+	FALSE                                  // false
+	FUN                                    // fun
+	FOR                                    // for
+	IF                                     // if
+	IN                                     // in
+	NIL                                    // nil
+	OR                                     // or
+	PRINT                                  // print
+	RETURN                                 // return
+	SUPER                                  // super
+	THIS                                   // this
+	TRUE                                   // true
+	VAR                                    // var
+	WHILE                                  // while
+	ILLEGAL                                // source the scanner reported an error for
 	EOF
 )
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":      AND,
+	"break":    BREAK,
+	"class":    CLASS,
+	"continue": CONTINUE,
+	"else":     ELSE,
+	"false":    FALSE,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"in":       IN,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"this":     THIS,
+	"true":     TRUE,
+	"var":      VAR,
+	"while":    WHILE,
 }
 
 type Token struct {
 	Type    TokenType
 	Lexme   string
 	Literal any
+	File    string
 	Line    int
+	Column  int
+	Start   int
+	End     int
 }
 
 type scanner struct {
-	code   string
-	start  int
-	pos    int
-	line   int
-	tokens []Token
+	file      string
+	code      string
+	start     int
+	pos       int
+	line      int
+	lineStart int
+	startLine int
+	// colLine, colOffset and col remember the last column computed so that
+	// long lines are not counted from their start for every token
+	colLine   int
+	colOffset int
+	col       int
+	startCol  int
+	tokens    []Token
+	errors    []error
+	// interpolations holds one entry per string interpolation being
+	// scanned, counting the braces opened inside its expression.
+	interpolations []interpolationState
 }
 
-func Scan(code string) ([]Token, error) {
-	scanner := scanner{code: code, pos: 0, line: 1}
-	err := scanner.scan()
-	if err != nil {
-		return nil, err
-	}
-	return scanner.tokens, nil
+type interpolationState struct {
+	open   Token
+	braces int
+}
+
+func Scan(code string) ([]Token, []error) {
+	return ScanFile("", code)
 }
 
-func (s *scanner) scan() error {
+// ScanFile scans code that was read from file, the name ends up in the span
+// of every token. Scanning does not stop at lexical errors, every error is
+// returned and the offending source becomes an ILLEGAL token so the tokens
+// can still be parsed.
+func ScanFile(file, code string) ([]Token, []error) {
+	scanner := scanner{file: file, code: code, pos: 0, line: 1, startLine: 1, startCol: 1}
+	scanner.scan()
+	return scanner.tokens, scanner.errors
+}
+
+func (s *scanner) scan() {
 	for !s.isAtEnd() {
+		s.startLine = s.line
+		s.startCol = s.column(s.start)
 		c := s.advance()
 		switch c {
 		case '(':
@@ -101,21 +162,70 @@ func (s *scanner) scan() error {
 		case ')':
 			s.addToken(RIGHT_PAREN, nil)
 		case '{':
+			if len(s.interpolations) > 0 {
+				s.interpolations[len(s.interpolations)-1].braces++
+			}
 			s.addToken(LEFT_BRACE, nil)
 		case '}':
+			if n := len(s.interpolations); n > 0 {
+				if s.interpolations[n-1].braces == 0 {
+					s.interpolations = s.interpolations[:n-1]
+					s.string(INTERPOLATION_MID, INTERPOLATION_END)
+					break
+				}
+				s.interpolations[n-1].braces--
+			}
 			s.addToken(RIGHT_BRACE, nil)
+		case '[':
+			s.addToken(LEFT_BRACKET, nil)
+		case ']':
+			s.addToken(RIGHT_BRACKET, nil)
 		case ',':
 			s.addToken(COMMA, nil)
+		case ':':
+			s.addToken(COLON, nil)
 		case '.':
 			s.addToken(DOT, nil)
 		case '-':
-			s.addToken(MINUS, nil)
+			if s.match('=') {
+				s.addToken(MINUS_EQUAL, nil)
+			} else {
+				s.addToken(MINUS, nil)
+			}
 		case '+':
-			s.addToken(PLUS, nil)
+			if s.match('=') {
+				s.addToken(PLUS_EQUAL, nil)
+			} else {
+				s.addToken(PLUS, nil)
+			}
 		case ';':
 			s.addToken(SEMICOLON, nil)
 		case '*':
-			s.addToken(STAR, nil)
+			if s.match('=') {
+				s.addToken(STAR_EQUAL, nil)
+			} else if s.match('*') {
+				s.addToken(STAR_STAR, nil)
+			} else {
+				s.addToken(STAR, nil)
+			}
+		case '%':
+			if s.match('=') {
+				s.addToken(PERCENT_EQUAL, nil)
+			} else {
+				s.addToken(PERCENT, nil)
+			}
+		case '~':
+			if s.match('/') {
+				s.addToken(TILDE_SLASH, nil)
+			} else {
+				s.addToken(TILDE, nil)
+			}
+		case '&':
+			s.addToken(AMPERSAND, nil)
+		case '|':
+			s.addToken(PIPE, nil)
+		case '^':
+			s.addToken(CARET, nil)
 		case '!':
 			if s.match('=') {
 				s.addToken(BANG_EQUAL, nil)
@@ -125,18 +235,24 @@ func (s *scanner) scan() error {
 		case '=':
 			if s.match('=') {
 				s.addToken(EQUAL_EQUAL, nil)
+			} else if s.match('>') {
+				s.addToken(ARROW, nil)
 			} else {
 				s.addToken(EQUAL, nil)
 			}
 		case '<':
 			if s.match('=') {
 				s.addToken(LESS_EQUAL, nil)
+			} else if s.match('<') {
+				s.addToken(LESS_LESS, nil)
 			} else {
 				s.addToken(LESS, nil)
 			}
 		case '>':
 			if s.match('=') {
 				s.addToken(GREATER_EQUAL, nil)
+			} else if s.match('>') {
+				s.addToken(GREATER_GREATER, nil)
 			} else {
 				s.addToken(GREATER, nil)
 			}
@@ -145,54 +261,23 @@ func (s *scanner) scan() error {
 				for s.peek(0) != '\n' && !s.isAtEnd() {
 					s.advance()
 				}
-			} else if s.peek(0) == '*' {
-				for s.peek(0) != '*' || s.peek(1) != '/' {
-					if s.advance() == '\n' {
-						s.line += 1
-					}
-				}
-				s.advance()
-				s.advance()
+			} else if s.match('*') {
+				s.blockComment()
+			} else if s.match('=') {
+				s.addToken(SLASH_EQUAL, nil)
 			} else {
 				s.addToken(SLASH, nil)
 			}
 		case '"':
-			for s.peek(0) != '"' && !s.isAtEnd() {
-				if s.peek(0) == '\n' {
-					s.line++
-				}
-				s.advance()
-			}
-
-			if s.isAtEnd() {
-				return fmt.Errorf("unterminated string at line %d", s.line)
-			}
-
-			s.advance()
-			s.addToken(STRING, s.code[s.start+1:s.pos-1])
+			s.string(INTERPOLATION, STRING)
 		case ' ':
 		case '\r':
 		case '\t':
 		case '\n':
-			s.line++
+			s.newline()
 		default:
 			if isDigit(c) {
-				for isDigit(s.peek(0)) {
-					s.advance()
-				}
-
-				if s.peek(0) == '.' && isDigit(s.peek(1)) {
-					s.advance()
-					for isDigit(s.peek(0)) {
-						s.advance()
-					}
-				}
-
-				num, err := strconv.ParseFloat(s.code[s.start:s.pos], 64)
-				if err != nil {
-					return fmt.Errorf("error while parsing number: %v", err)
-				}
-				s.addToken(NUMBER, num)
+				s.number(c)
 			} else if isApha(c) {
 				for isAlpaNumeric(s.peek(0)) {
 					s.advance()
@@ -203,56 +288,352 @@ func (s *scanner) scan() error {
 					token = IDENTIFIER
 				}
 				s.addToken(token, nil)
+			} else if c == utf8.RuneError && s.pos-s.start == 1 {
+				// advance already reported the invalid encoding
+				s.addToken(ILLEGAL, nil)
 			} else {
-				return fmt.Errorf("unexpected character '%c' at line %d", c, s.line)
+				s.error(fmt.Sprintf("unexpected character '%c'", c))
 			}
 		}
 		s.start = s.pos
 	}
+	s.startLine = s.line
+	s.startCol = s.column(s.start)
+	if len(s.interpolations) > 0 {
+		// the parser skips the ILLEGAL token instead of reporting the
+		// missing end of the string again
+		s.errors = append(s.errors, ParseError{s.interpolations[0].open, "unterminated string interpolation", "lexical"})
+		s.addToken(ILLEGAL, nil)
+	}
 	s.addToken(EOF, nil)
-	return nil
 }
 
 func (s *scanner) addToken(tokenType TokenType, literal any) {
-	s.tokens = append(s.tokens, Token{Type: tokenType, Literal: literal, Line: s.line, Lexme: s.code[s.start:s.pos]})
+	s.tokens = append(s.tokens, Token{
+		Type:    tokenType,
+		Lexme:   s.code[s.start:s.pos],
+		Literal: literal,
+		File:    s.file,
+		Line:    s.startLine,
+		Column:  s.startCol,
+		Start:   s.start,
+		End:     s.pos,
+	})
 }
 
-func (s *scanner) advance() byte {
-	c := s.code[s.pos]
-	s.pos++
-	return c
+// number scans a number literal whose first digit was just consumed. Besides
+// decimals with an optional fraction and exponent it accepts 0x hexadecimal
+// and 0b binary integers, digits may be separated by underscores.
+func (s *scanner) number(first rune) {
+	base, name := 10, "number literal"
+	if first == '0' && (s.peek(0) == 'x' || s.peek(0) == 'X') {
+		base, name = 16, "hexadecimal literal"
+		s.advance()
+	} else if first == '0' && (s.peek(0) == 'b' || s.peek(0) == 'B') {
+		base, name = 2, "binary literal"
+		s.advance()
+	}
+	digits := s.pos
+	if base == 10 {
+		digits = s.start
+		s.digits()
+		if s.peek(0) == '.' && isDigit(s.peek(1)) {
+			s.advance()
+			s.digits()
+		}
+		if e := s.peek(0); (e == 'e' || e == 'E') &&
+			(isDigit(s.peek(1)) || (s.peek(1) == '+' || s.peek(1) == '-') && isDigit(s.peek(2))) {
+			s.advance()
+			if !s.match('+') {
+				s.match('-')
+			}
+			s.digits()
+		}
+	}
+	valid := s.pos
+	// letters and digits right after the literal are reported as part of it
+	for isAlpaNumeric(s.peek(0)) {
+		s.advance()
+	}
+
+	text := s.code[digits:s.pos]
+	if base == 10 && valid < s.pos {
+		if c := s.code[valid]; c == 'e' || c == 'E' {
+			s.error("exponent has no digits")
+		} else {
+			c, _ := utf8.DecodeRuneInString(s.code[valid:])
+			s.error(fmt.Sprintf("invalid character '%c' in %s", c, name))
+		}
+		return
+	}
+	if text == "" {
+		s.error(name + " has no digits")
+		return
+	}
+	for idx, c := range text {
+		if c == '_' {
+			if idx == 0 || idx == len(text)-1 || !isDigitOf(rune(text[idx-1]), base) || !isDigitOf(rune(text[idx+1]), base) {
+				s.error("'_' must separate successive digits")
+				return
+			}
+		} else if base != 10 && !isDigitOf(c, base) {
+			s.error(fmt.Sprintf("invalid digit '%c' in %s", c, name))
+			return
+		}
+	}
+
+	// literals without a fraction or exponent are integers, unless they are
+	// too large for an int64 and become floats like overflowing arithmetic
+	text = strings.ReplaceAll(text, "_", "")
+	if base == 10 && strings.ContainsAny(text, ".eE") {
+		num, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			s.error(name + " out of range")
+			return
+		}
+		s.addToken(NUMBER, num)
+		return
+	}
+	num, err := strconv.ParseUint(text, base, 64)
+	if err != nil && base == 10 {
+		float, _ := strconv.ParseFloat(text, 64)
+		s.addToken(NUMBER, float)
+		return
+	}
+	if err != nil {
+		s.error(name + " out of range")
+		return
+	}
+	if num > math.MaxInt64 {
+		s.addToken(NUMBER, float64(num))
+		return
+	}
+	s.addToken(NUMBER, int64(num))
+}
+
+// digits consumes decimal digits and the underscores between them.
+func (s *scanner) digits() {
+	for isDigit(s.peek(0)) || s.peek(0) == '_' {
+		s.advance()
+	}
+}
+
+// string scans a string literal after its opening quote, or the rest of one
+// after the } closing an interpolated expression. A part that ends in ${
+// becomes an interpolation token and scanning returns to ordinary tokens
+// until the matching }, the part ending the string becomes an end token.
+func (s *scanner) string(interpolation, end TokenType) {
+	b := &strings.Builder{}
+	for !s.isAtEnd() {
+		c := s.advance()
+		switch c {
+		case '"':
+			s.addToken(end, b.String())
+			return
+		case '$':
+			if s.match('{') {
+				s.addToken(interpolation, b.String())
+				s.interpolations = append(s.interpolations, interpolationState{s.tokens[len(s.tokens)-1], 0})
+				return
+			}
+		case '\\':
+			s.escape(b)
+			continue
+		case '\n':
+			s.newline()
+		}
+		b.WriteRune(c)
+	}
+	s.error("unterminated string")
 }
 
-func (s *scanner) peek(offset int) byte {
+// escape writes the character escaped by the backslash just consumed.
+func (s *scanner) escape(b *strings.Builder) {
 	if s.isAtEnd() {
+		return
+	}
+	start := s.pos - 1
+	switch c := s.advance(); c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case '0':
+		b.WriteByte(0)
+	case '"', '\\', '$':
+		b.WriteRune(c)
+	case 'u':
+		s.unicodeEscape(b, start)
+	case '\n':
+		// report before moving on, the error is on the line that ends here
+		s.errorAt(start, start+1, "unknown escape sequence at end of line")
+		s.newline()
+	default:
+		s.errorAt(start, s.pos, fmt.Sprintf("unknown escape sequence '\\%c'", c))
+	}
+}
+
+// unicodeEscape reads the {hex} part of a \u{hex} escape that started at
+// start.
+func (s *scanner) unicodeEscape(b *strings.Builder, start int) {
+	if !s.match('{') {
+		s.errorAt(start, s.pos, "expected '{' after '\\u'")
+		return
+	}
+	digits := s.pos
+	for isHexDigit(s.peek(0)) {
+		s.advance()
+	}
+	hex := s.code[digits:s.pos]
+	if !s.match('}') {
+		s.errorAt(start, s.pos, "expected '}' after unicode escape")
+		return
+	}
+	if len(hex) == 0 || len(hex) > 6 {
+		s.errorAt(start, s.pos, "unicode escape must have 1 to 6 hex digits")
+		return
+	}
+	r, _ := strconv.ParseUint(hex, 16, 32)
+	if !utf8.ValidRune(rune(r)) {
+		s.errorAt(start, s.pos, fmt.Sprintf("invalid unicode code point U+%X", r))
+		return
+	}
+	b.WriteRune(rune(r))
+}
+
+// blockComment skips a block comment whose opening /* was just consumed,
+// comments nest so every /* needs its own */.
+func (s *scanner) blockComment() {
+	for depth := 1; depth > 0; {
+		if s.isAtEnd() {
+			// report just the opening /* rather than the rest of the file
+			end := s.pos
+			s.pos = s.start + 2
+			s.error("unterminated block comment")
+			s.pos = end
+			return
+		}
+		switch {
+		case s.peek(0) == '/' && s.peek(1) == '*':
+			s.pos += 2
+			depth++
+		case s.peek(0) == '*' && s.peek(1) == '/':
+			s.pos += 2
+			depth--
+		default:
+			if s.advance() == '\n' {
+				s.newline()
+			}
+		}
+	}
+}
+
+// error reports a lexical error covering the lexme scanned so far and
+// replaces it with an ILLEGAL token.
+func (s *scanner) error(message string) {
+	s.addToken(ILLEGAL, nil)
+	s.errors = append(s.errors, ParseError{s.tokens[len(s.tokens)-1], message, "lexical"})
+}
+
+// errorAt reports a lexical error for code[start:end] on the current line
+// without ending the token being scanned.
+func (s *scanner) errorAt(start, end int, message string) {
+	s.errors = append(s.errors, ParseError{Token{
+		Type:   ILLEGAL,
+		Lexme:  s.code[start:end],
+		File:   s.file,
+		Line:   s.line,
+		Column: s.column(start),
+		Start:  start,
+		End:    end,
+	}, message, "lexical"})
+}
+
+// column returns the column of the byte at offset on the current line,
+// counted in runes. Offsets mostly come in increasing order, so counting
+// continues from the previous one.
+func (s *scanner) column(offset int) int {
+	if s.colLine != s.line || offset < s.colOffset {
+		s.colLine, s.colOffset, s.col = s.line, s.lineStart, 1
+	}
+	s.col += utf8.RuneCountInString(s.code[s.colOffset:offset])
+	s.colOffset = offset
+	return s.col
+}
+
+// newline records that the character just consumed was a line break.
+func (s *scanner) newline() {
+	s.line++
+	s.lineStart = s.pos
+}
+
+// advance consumes the next rune, invalid encodings are reported and
+// consumed one byte at a time as utf8.RuneError.
+func (s *scanner) advance() rune {
+	c, size := utf8.DecodeRuneInString(s.code[s.pos:])
+	if c == utf8.RuneError && size == 1 {
+		s.errorAt(s.pos, s.pos+1, fmt.Sprintf("invalid UTF-8 encoding %q", s.code[s.pos:s.pos+1]))
+	}
+	s.pos += size
+	return c
+}
+
+// peek returns the rune offset runes ahead without consuming anything, or 0
+// past the end of the code.
+func (s *scanner) peek(offset int) rune {
+	pos := s.pos
+	for ; offset > 0 && pos < len(s.code); offset-- {
+		_, size := utf8.DecodeRuneInString(s.code[pos:])
+		pos += size
+	}
+	if pos >= len(s.code) {
 		return 0
 	}
-	return s.code[s.pos+offset]
+	c, _ := utf8.DecodeRuneInString(s.code[pos:])
+	return c
 }
 
 func (s *scanner) isAtEnd() bool {
 	return len(s.code) <= s.pos
 }
 
-func (s *scanner) match(expected byte) bool {
+func (s *scanner) match(expected rune) bool {
 	if s.isAtEnd() {
 		return false
 	}
-	if s.code[s.pos] != expected {
+	if s.peek(0) != expected {
 		return false
 	}
-	s.pos++
+	s.advance()
 	return true
 }
 
-func isDigit(c byte) bool {
+// isDigit only accepts ascii digits, number literals are not localized.
+func isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 
-func isApha(c byte) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+func isHexDigit(c rune) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isDigitOf(c rune, base int) bool {
+	switch base {
+	case 2:
+		return c == '0' || c == '1'
+	case 16:
+		return isHexDigit(c)
+	default:
+		return isDigit(c)
+	}
+}
+
+func isApha(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
 }
 
-func isAlpaNumeric(c byte) bool {
-	return isApha(c) || isDigit(c)
+func isAlpaNumeric(c rune) bool {
+	return isApha(c) || unicode.IsDigit(c)
 }
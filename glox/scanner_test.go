@@ -0,0 +1,264 @@
+package glox
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTokenPositions(t *testing.T) {
+	tokens, errs := ScanFile("test.lox", "var answer = 42;\n  print answer;")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	want := []Span{
+		{"test.lox", 1, 1, 0, 3},
+		{"test.lox", 1, 5, 4, 10},
+		{"test.lox", 1, 12, 11, 12},
+		{"test.lox", 1, 14, 13, 15},
+		{"test.lox", 1, 16, 15, 16},
+		{"test.lox", 2, 3, 19, 24},
+		{"test.lox", 2, 9, 25, 31},
+		{"test.lox", 2, 15, 31, 32},
+	}
+	if len(tokens) != len(want)+1 {
+		t.Fatalf("got %v tokens, want %v and EOF", len(tokens), len(want))
+	}
+	for idx, span := range want {
+		if got := tokens[idx].Span(); got != span {
+			t.Errorf("token %q is at %+v, want %+v", tokens[idx].Lexme, got, span)
+		}
+	}
+}
+
+func TestScanReportsEveryError(t *testing.T) {
+	tokens, errs := Scan("var a = @;\nprint #a;\nprint \"open")
+	want := []string{
+		"1:9: unexpected character '@'",
+		"2:7: unexpected character '#'",
+		"3:7: unterminated string",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got errors %v, want %v", errs, want)
+	}
+	for idx, err := range errs {
+		parseErr := err.(ParseError)
+		if got := fmt.Sprintf("%v: %v", parseErr.Token.Span(), parseErr.Message); got != want[idx] {
+			t.Errorf("got error %q, want %q", got, want[idx])
+		}
+	}
+
+	var types []TokenType
+	for _, token := range tokens {
+		types = append(types, token.Type)
+	}
+	wantTypes := []TokenType{VAR, IDENTIFIER, EQUAL, ILLEGAL, SEMICOLON, PRINT, ILLEGAL, IDENTIFIER, SEMICOLON, PRINT, ILLEGAL, EOF}
+	if fmt.Sprint(types) != fmt.Sprint(wantTypes) {
+		t.Errorf("got tokens %v, want %v", types, wantTypes)
+	}
+}
+
+func TestBlockComments(t *testing.T) {
+	tests := []struct {
+		code string
+		want []TokenType
+	}{
+		{"a /* b */ c", []TokenType{IDENTIFIER, IDENTIFIER, EOF}},
+		{"a /* /* b */ c */ d", []TokenType{IDENTIFIER, IDENTIFIER, EOF}},
+		{"a /* b \n * c \n */ d", []TokenType{IDENTIFIER, IDENTIFIER, EOF}},
+		{"a /**/ b /***/ c", []TokenType{IDENTIFIER, IDENTIFIER, IDENTIFIER, EOF}},
+		{"a / b /* // */ c", []TokenType{IDENTIFIER, SLASH, IDENTIFIER, IDENTIFIER, EOF}},
+	}
+	for _, test := range tests {
+		tokens, errs := Scan(test.code)
+		if len(errs) != 0 {
+			t.Errorf("Scan(%q) failed: %v", test.code, errs)
+			continue
+		}
+		var types []TokenType
+		for _, token := range tokens {
+			types = append(types, token.Type)
+		}
+		if fmt.Sprint(types) != fmt.Sprint(test.want) {
+			t.Errorf("Scan(%q) = %v, want %v", test.code, types, test.want)
+		}
+	}
+
+	tokens, _ := Scan("/* a */\nb /* c\n/* d */ e")
+	if line := tokens[0].Line; line != 2 {
+		t.Errorf("b is on line %v, want 2", line)
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	for _, code := range []string{"a\n  /* b", "a\n  /* /* b */", "a\n  /*"} {
+		_, errs := Scan(code)
+		if len(errs) != 1 {
+			t.Errorf("Scan(%q) returned %v, want one error", code, errs)
+			continue
+		}
+		parseErr := errs[0].(ParseError)
+		if parseErr.Message != "unterminated block comment" || parseErr.Token.Lexme != "/*" || parseErr.Token.Line != 2 || parseErr.Token.Column != 3 {
+			t.Errorf("Scan(%q) reported %q at %v, want the opening /* at 2:3", code, parseErr.Message, parseErr.Token.Span())
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	tokens, errs := Scan("var café = \"naïve ☃\";\nvar 変数 = café; π")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	want := []struct {
+		lexme        string
+		line, column int
+	}{
+		{"var", 1, 1}, {"café", 1, 5}, {"=", 1, 10}, {`"naïve ☃"`, 1, 12}, {";", 1, 21},
+		{"var", 2, 1}, {"変数", 2, 5}, {"=", 2, 8}, {"café", 2, 10}, {";", 2, 14}, {"π", 2, 16},
+	}
+	for idx, w := range want {
+		token := tokens[idx]
+		if token.Lexme != w.lexme || token.Line != w.line || token.Column != w.column {
+			t.Errorf("token %v is %q at %v:%v, want %q at %v:%v", idx, token.Lexme, token.Line, token.Column, w.lexme, w.line, w.column)
+		}
+	}
+	if tokens[1].Type != IDENTIFIER || tokens[6].Type != IDENTIFIER || tokens[10].Type != IDENTIFIER {
+		t.Errorf("unicode letters should scan as identifiers")
+	}
+	if literal := tokens[3].Literal; literal != "naïve ☃" {
+		t.Errorf("string literal is %q", literal)
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	_, errs := Scan("var ü = 1;\nprint \"a\xffb\" + \xfe;")
+	want := []string{
+		`2:9: invalid UTF-8 encoding "\xff"`,
+		`2:15: invalid UTF-8 encoding "\xfe"`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got errors %v, want %v", errs, want)
+	}
+	for idx, err := range errs {
+		parseErr := err.(ParseError)
+		if got := fmt.Sprintf("%v: %v", parseErr.Token.Span(), parseErr.Message); got != want[idx] {
+			t.Errorf("got error %q, want %q", got, want[idx])
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tokens, errs := Scan(`"tab\tnewline\nquote\"backslash\\dollar\$\u{48}\u{1F600}\0"`)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	want := "tab\tnewline\nquote\"backslash\\dollar$H\U0001F600\x00"
+	if tokens[0].Type != STRING || tokens[0].Literal != want {
+		t.Errorf("scanned %v %q, want a string %q", tokens[0].Type, tokens[0].Literal, want)
+	}
+}
+
+func TestStringEscapeErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{`"a\qb"`, `1:3: unknown escape sequence '\q'`},
+		{`"\u{110000}"`, "1:2: invalid unicode code point U+110000"},
+		{`"\u{}"`, "1:2: unicode escape must have 1 to 6 hex digits"},
+		{`"\u{1234567}"`, "1:2: unicode escape must have 1 to 6 hex digits"},
+		{`"\u12"`, `1:2: expected '{' after '\u'`},
+		{`"\u{12"`, "1:2: expected '}' after unicode escape"},
+		{"\"a\\\nb\"", "1:3: unknown escape sequence at end of line"},
+		{"x\n  \"\\\n\"", "2:4: unknown escape sequence at end of line"},
+	}
+	for _, test := range tests {
+		_, errs := Scan(test.code)
+		if len(errs) == 0 {
+			t.Errorf("Scan(%v) succeeded, want %q", test.code, test.want)
+			continue
+		}
+		parseErr := errs[0].(ParseError)
+		if got := fmt.Sprintf("%v: %v", parseErr.Token.Span(), parseErr.Message); got != test.want {
+			t.Errorf("Scan(%v) reported %q, want %q", test.code, got, test.want)
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		code string
+		want any
+	}{
+		{"42", int64(42)},
+		{"3.25", 3.25},
+		{"1_000_000", int64(1000000)},
+		{"0.000_1", 0.0001},
+		{"1e3", 1000.0},
+		{"2.5E-2", 0.025},
+		{"1e+2", 100.0},
+		{"0xff", int64(255)},
+		{"0XFF_FF", int64(65535)},
+		{"0b1010", int64(10)},
+		{"0B1_0", int64(2)},
+		{"0", int64(0)},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"9223372036854775808", 9223372036854775808.0},
+	}
+	for _, test := range tests {
+		tokens, errs := Scan(test.code)
+		if len(errs) != 0 {
+			t.Errorf("Scan(%q) failed: %v", test.code, errs)
+			continue
+		}
+		if tokens[0].Type != NUMBER || tokens[0].Literal != test.want || tokens[0].Lexme != test.code {
+			t.Errorf("Scan(%q) = %v %q %v, want the number %v", test.code, tokens[0].Type, tokens[0].Lexme, tokens[0].Literal, test.want)
+		}
+	}
+}
+
+func TestNumberLiteralErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"1_", "'_' must separate successive digits"},
+		{"1__0", "'_' must separate successive digits"},
+		{"0x_1", "'_' must separate successive digits"},
+		{"0x", "hexadecimal literal has no digits"},
+		{"0b", "binary literal has no digits"},
+		{"0b102", "invalid digit '2' in binary literal"},
+		{"0xfg", "invalid digit 'g' in hexadecimal literal"},
+		{"12abc", "invalid character 'a' in number literal"},
+		{"1e", "exponent has no digits"},
+		{"0x1_0000_0000_0000_0000", "hexadecimal literal out of range"},
+	}
+	for _, test := range tests {
+		tokens, errs := Scan(test.code + " x")
+		if len(errs) != 1 {
+			t.Errorf("Scan(%q) returned %v, want %q", test.code, errs, test.want)
+			continue
+		}
+		parseErr := errs[0].(ParseError)
+		if parseErr.Message != test.want || parseErr.Token.Lexme != test.code {
+			t.Errorf("Scan(%q) reported %q at %q, want %q", test.code, parseErr.Message, parseErr.Token.Lexme, test.want)
+		}
+		if tokens[1].Type != IDENTIFIER {
+			t.Errorf("Scan(%q) did not recover after the literal", test.code)
+		}
+	}
+}
+
+func TestColumnsOnLongLine(t *testing.T) {
+	line := strings.Repeat("é + ", 10000) + "é /* ü"
+	tokens, errs := Scan("x\n" + line)
+	for idx, token := range tokens[1 : len(tokens)-1] {
+		if want := 1 + 2*idx; token.Column != want {
+			t.Fatalf("token %v %q is at column %v, want %v", idx, token.Lexme, token.Column, want)
+		}
+	}
+	// the error is reported after the scanner moved past it
+	if len(errs) != 1 || errs[0].(ParseError).Token.Column != 40003 {
+		t.Errorf("got %v, want an unterminated comment at 2:40003", errs)
+	}
+}
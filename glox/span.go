@@ -0,0 +1,85 @@
+package glox
+
+import "fmt"
+
+// Span is a range of source code. Start and End are byte offsets with End
+// being exclusive, Line and Column describe where the range starts.
+type Span struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+func (s Span) IsZero() bool {
+	return s == Span{}
+}
+
+func (s Span) String() string {
+	if s.File == "" {
+		return fmt.Sprintf("%v:%v", s.Line, s.Column)
+	}
+	return fmt.Sprintf("%v:%v:%v", s.File, s.Line, s.Column)
+}
+
+func (t Token) Span() Span {
+	return Span{t.File, t.Line, t.Column, t.Start, t.End}
+}
+
+// joinSpans returns the smallest span covering all non zero spans.
+func joinSpans(spans ...Span) Span {
+	var joined Span
+	for _, span := range spans {
+		if span.IsZero() {
+			continue
+		}
+		if joined.IsZero() {
+			joined = span
+			continue
+		}
+		if span.Start < joined.Start {
+			joined.Line, joined.Column, joined.Start = span.Line, span.Column, span.Start
+		}
+		if span.End > joined.End {
+			joined.End = span.End
+		}
+	}
+	return joined
+}
+
+// spanOf returns the span of a field of an ast node.
+func spanOf(field any) Span {
+	switch field := field.(type) {
+	case Token:
+		return field.Span()
+	case interface{ Span() Span }:
+		return field.Span()
+	case []Token:
+		spans := make([]Span, len(field))
+		for idx, t := range field {
+			spans[idx] = t.Span()
+		}
+		return joinSpans(spans...)
+	case []Expr:
+		spans := make([]Span, len(field))
+		for idx, expr := range field {
+			spans[idx] = spanOf(expr)
+		}
+		return joinSpans(spans...)
+	case []Stmt:
+		spans := make([]Span, len(field))
+		for idx, stmt := range field {
+			spans[idx] = spanOf(stmt)
+		}
+		return joinSpans(spans...)
+	case []*Function:
+		spans := make([]Span, len(field))
+		for idx, function := range field {
+			spans[idx] = function.Span()
+		}
+		return joinSpans(spans...)
+	default:
+		return Span{}
+	}
+}
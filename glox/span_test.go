@@ -0,0 +1,32 @@
+package glox
+
+import "testing"
+
+func TestExprSpans(t *testing.T) {
+	code := "print a + b * c;\nprint f(x,\n  -y);\nobj.field = 1;"
+	stmts, errs := ParseCode(code)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	sum := stmts[0].(*PrintStmt).Expr.(*BinaryExpr)
+	call := stmts[1].(*PrintStmt).Expr.(*CallExpr)
+	tests := []struct {
+		expr Expr
+		want string
+	}{
+		{sum, "a + b * c"},
+		{sum.Right, "b * c"},
+		{call, "f(x,\n  -y)"},
+		{call.Arguments[1], "-y"},
+		{stmts[2].(*ExprStmt).Expr, "obj.field = 1"},
+	}
+	for _, test := range tests {
+		span := test.expr.Span()
+		if got := code[span.Start:span.End]; got != test.want {
+			t.Errorf("span of %T covers %q, want %q", test.expr, got, test.want)
+		}
+	}
+	if span := call.Arguments[1].Span(); span.Line != 3 || span.Column != 3 {
+		t.Errorf("-y starts at %v, want 3:3", span)
+	}
+}
@@ -0,0 +1,400 @@
+package glox
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+type ValueType uint8
+
+const (
+	NIL_VALUE ValueType = iota
+	BOOL_VALUE
+	NUMBER_VALUE
+	INT_VALUE
+	OBJECT_VALUE
+)
+
+// Value is the representation of a lox value in both the interpreter and the
+// bytecode vm. Numbers and booleans live in num so that they never need to be
+// boxed, integers keep the bits of their int64 there. Everything else
+// (strings, functions, classes, ...) is stored in obj.
+//
+// Integer arithmetic stays integral and is promoted to a float when it
+// overflows. Mixing an integer with a float gives a float, and dividing two
+// integers gives an integer when the division is exact.
+type Value struct {
+	Type ValueType
+	num  float64
+	obj  any
+}
+
+var NilValue = Value{}
+
+func BoolValue(b bool) Value {
+	if b {
+		return Value{BOOL_VALUE, 1, nil}
+	}
+	return Value{BOOL_VALUE, 0, nil}
+}
+
+func NumberValue(n float64) Value {
+	return Value{NUMBER_VALUE, n, nil}
+}
+
+func IntValue(i int64) Value {
+	return Value{INT_VALUE, math.Float64frombits(uint64(i)), nil}
+}
+
+func ObjectValue(o any) Value {
+	return Value{OBJECT_VALUE, 0, o}
+}
+
+// valueOf wraps a go value, like the literal of a token, in a Value. Objects
+// keep the interface they were passed in so wrapping them does not allocate.
+func valueOf(v any) Value {
+	switch v := v.(type) {
+	case nil:
+		return NilValue
+	case Value:
+		return v
+	case bool:
+		return BoolValue(v)
+	case float64:
+		return NumberValue(v)
+	case int64:
+		return IntValue(v)
+	}
+	return Value{OBJECT_VALUE, 0, v}
+}
+
+func (v Value) IsNil() bool {
+	return v.Type == NIL_VALUE
+}
+
+func (v Value) IsBool() bool {
+	return v.Type == BOOL_VALUE
+}
+
+// IsNumber reports whether v is a float or an integer.
+func (v Value) IsNumber() bool {
+	return v.Type == NUMBER_VALUE || v.Type == INT_VALUE
+}
+
+func (v Value) IsInt() bool {
+	return v.Type == INT_VALUE
+}
+
+func (v Value) IsString() bool {
+	_, ok := v.obj.(string)
+	return ok
+}
+
+func (v Value) AsBool() bool {
+	return v.num != 0
+}
+
+// AsNumber returns v as a float, converting integers.
+func (v Value) AsNumber() float64 {
+	if v.Type == INT_VALUE {
+		return float64(v.AsInt())
+	}
+	return v.num
+}
+
+func (v Value) AsInt() int64 {
+	return int64(math.Float64bits(v.num))
+}
+
+func (v Value) AsString() string {
+	s, _ := v.obj.(string)
+	return s
+}
+
+func (v Value) AsObject() any {
+	return v.obj
+}
+
+func (v Value) IsTruthy() bool {
+	switch v.Type {
+	case NIL_VALUE:
+		return false
+	case BOOL_VALUE:
+		return v.AsBool()
+	default:
+		return true
+	}
+}
+
+// Equals compares by value, integers and floats are equal when they hold the
+// same number. Strings compare by their contents and every other object,
+// including lists and maps, by identity.
+func (v Value) Equals(other Value) bool {
+	if v.IsInt() && other.IsInt() {
+		return v.AsInt() == other.AsInt()
+	}
+	if v.IsInt() && other.IsNumber() {
+		return intEqualsFloat(v.AsInt(), other.num)
+	}
+	if v.IsNumber() && other.IsInt() {
+		return intEqualsFloat(other.AsInt(), v.num)
+	}
+	if v.IsNumber() && other.IsNumber() {
+		return v.AsNumber() == other.AsNumber()
+	}
+	if v.Type != other.Type {
+		return false
+	}
+	switch v.Type {
+	case NIL_VALUE:
+		return true
+	case BOOL_VALUE:
+		return v.num == other.num
+	default:
+		if !isComparable(v.obj) || !isComparable(other.obj) {
+			return false
+		}
+		return v.obj == other.obj
+	}
+}
+
+// intEqualsFloat compares exactly, converting i to a float could round it
+// onto f. hashKey relies on this to give equal numbers the same key.
+func intEqualsFloat(i int64, f float64) bool {
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 && int64(f) == i
+}
+
+// String formats the value the way lox prints it. Instances with a toString
+// method are formatted by the interpreter or vm, which can call the method.
+func (v Value) String() string {
+	switch v.Type {
+	case NIL_VALUE:
+		return "nil"
+	case BOOL_VALUE:
+		return strconv.FormatBool(v.AsBool())
+	case NUMBER_VALUE:
+		return formatNumber(v.num)
+	case INT_VALUE:
+		return strconv.FormatInt(v.AsInt(), 10)
+	}
+	switch obj := v.obj.(type) {
+	case string:
+		return obj
+	case interface{ String() string }:
+		return obj.String()
+	default:
+		return "<object>"
+	}
+}
+
+// formatNumber prints whole numbers without a fraction and everything else
+// in the shortest form that reads back as the same float.
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "Infinity"
+	case math.IsInf(n, -1):
+		return "-Infinity"
+	case math.Abs(n) < 1e21:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	default:
+		return strconv.FormatFloat(n, 'g', -1, 64)
+	}
+}
+
+// binaryOp applies the operator of a binary expression to its evaluated
+// operands. The returned error only carries the message, callers attach the
+// position they know about.
+func binaryOp(op TokenType, left, right Value) (Value, error) {
+	switch op {
+	case EQUAL_EQUAL:
+		return BoolValue(left.Equals(right)), nil
+	case BANG_EQUAL:
+		return BoolValue(!left.Equals(right)), nil
+	}
+
+	switch {
+	case left.IsNumber():
+		if !right.IsNumber() {
+			return NilValue, errors.New("binary expr with a left num must have a right num")
+		}
+		if left.IsInt() && right.IsInt() {
+			return intOp(op, left.AsInt(), right.AsInt())
+		}
+		l, r := left.AsNumber(), right.AsNumber()
+		switch op {
+		case PLUS:
+			return NumberValue(l + r), nil
+		case MINUS:
+			return NumberValue(l - r), nil
+		case SLASH:
+			if r == 0 {
+				return NilValue, errors.New("cannot divide by 0")
+			}
+			return NumberValue(l / r), nil
+		case STAR:
+			return NumberValue(l * r), nil
+		case GREATER:
+			return BoolValue(l > r), nil
+		case GREATER_EQUAL:
+			return BoolValue(l >= r), nil
+		case LESS:
+			return BoolValue(l < r), nil
+		case LESS_EQUAL:
+			return BoolValue(l <= r), nil
+		case STAR_STAR:
+			return NumberValue(math.Pow(l, r)), nil
+		case PERCENT, TILDE_SLASH, AMPERSAND, PIPE, CARET, LESS_LESS, GREATER_GREATER:
+			return NilValue, fmt.Errorf("binary expr with '%v' only support integers", integerOperators[op])
+		default:
+			panic("Unreachable")
+		}
+	case left.IsString():
+		if !right.IsString() {
+			return NilValue, errors.New("binary epxr with a left string must have a right string")
+		}
+		if op != PLUS {
+			return NilValue, errors.New("binary expr on strings only support +")
+		}
+		return ObjectValue(left.AsString() + right.AsString()), nil
+	default:
+		return NilValue, errors.New("binary expr does not accept this type")
+	}
+}
+
+// integerOperators are the operators that only accept integers.
+var integerOperators = map[TokenType]string{
+	PERCENT:         "%",
+	TILDE_SLASH:     "~/",
+	AMPERSAND:       "&",
+	PIPE:            "|",
+	CARET:           "^",
+	LESS_LESS:       "<<",
+	GREATER_GREATER: ">>",
+}
+
+// intOp applies an arithmetic or comparison operator to two integers.
+// Results that do not fit an int64 are computed as floats instead.
+func intOp(op TokenType, l, r int64) (Value, error) {
+	switch op {
+	case PLUS:
+		sum := l + r
+		if (l >= 0) == (r >= 0) && (sum >= 0) != (l >= 0) {
+			return NumberValue(float64(l) + float64(r)), nil
+		}
+		return IntValue(sum), nil
+	case MINUS:
+		diff := l - r
+		if (l >= 0) != (r >= 0) && (diff >= 0) != (l >= 0) {
+			return NumberValue(float64(l) - float64(r)), nil
+		}
+		return IntValue(diff), nil
+	case STAR:
+		product := l * r
+		if l != 0 && (product/l != r || (l == -1 && r == math.MinInt64)) {
+			return NumberValue(float64(l) * float64(r)), nil
+		}
+		return IntValue(product), nil
+	case SLASH:
+		if r == 0 {
+			return NilValue, errors.New("cannot divide by 0")
+		}
+		if l%r != 0 || (l == math.MinInt64 && r == -1) {
+			return NumberValue(float64(l) / float64(r)), nil
+		}
+		return IntValue(l / r), nil
+	case TILDE_SLASH:
+		if r == 0 {
+			return NilValue, errors.New("cannot divide by 0")
+		}
+		if l == math.MinInt64 && r == -1 {
+			return NumberValue(-float64(l)), nil
+		}
+		// round towards negative infinity rather than zero
+		quotient := l / r
+		if l%r != 0 && (l < 0) != (r < 0) {
+			quotient--
+		}
+		return IntValue(quotient), nil
+	case PERCENT:
+		if r == 0 {
+			return NilValue, errors.New("cannot take the remainder of a division by 0")
+		}
+		// the remainder has the sign of the divisor, matching ~/
+		remainder := l % r
+		if remainder != 0 && (remainder < 0) != (r < 0) {
+			remainder += r
+		}
+		return IntValue(remainder), nil
+	case STAR_STAR:
+		return intPow(l, r), nil
+	case AMPERSAND:
+		return IntValue(l & r), nil
+	case PIPE:
+		return IntValue(l | r), nil
+	case CARET:
+		return IntValue(l ^ r), nil
+	case LESS_LESS, GREATER_GREATER:
+		// shifts work on the bits and never promote, >> keeps the sign
+		if r < 0 {
+			return NilValue, errors.New("shift count must not be negative")
+		}
+		if op == LESS_LESS {
+			return IntValue(l << min(r, 64)), nil
+		}
+		return IntValue(l >> min(r, 64)), nil
+	case GREATER:
+		return BoolValue(l > r), nil
+	case GREATER_EQUAL:
+		return BoolValue(l >= r), nil
+	case LESS:
+		return BoolValue(l < r), nil
+	case LESS_EQUAL:
+		return BoolValue(l <= r), nil
+	default:
+		panic("Unreachable")
+	}
+}
+
+// intPow raises base to exp by squaring, negative exponents and results that
+// overflow are computed as floats.
+func intPow(base, exp int64) Value {
+	if exp < 0 {
+		return NumberValue(math.Pow(float64(base), float64(exp)))
+	}
+	result := IntValue(1)
+	for square := IntValue(base); exp > 0; exp >>= 1 {
+		if exp&1 == 1 {
+			result, _ = binaryOp(STAR, result, square)
+		}
+		if exp > 1 {
+			square, _ = binaryOp(STAR, square, square)
+		}
+	}
+	return result
+}
+
+// unaryOp applies a prefix operator other than ! to its evaluated operand.
+func unaryOp(op TokenType, v Value) (Value, error) {
+	switch op {
+	case MINUS:
+		if v.IsInt() && v.AsInt() != math.MinInt64 {
+			return IntValue(-v.AsInt()), nil
+		}
+		if !v.IsNumber() {
+			return NilValue, errors.New("negation can only be done on numbers")
+		}
+		return NumberValue(-v.AsNumber()), nil
+	case TILDE:
+		if !v.IsInt() {
+			return NilValue, errors.New("bitwise not can only be done on integers")
+		}
+		return IntValue(^v.AsInt()), nil
+	default:
+		panic("Unreachable")
+	}
+}
@@ -0,0 +1,595 @@
+package glox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+const maxFrames = 4096
+
+// binaryOpTokens maps binary opcodes back to the operator they were compiled
+// from so the vm can share binaryOp with the interpreter.
+var binaryOpTokens = [...]TokenType{
+	OP_EQUAL:         EQUAL_EQUAL,
+	OP_NOT_EQUAL:     BANG_EQUAL,
+	OP_GREATER:       GREATER,
+	OP_GREATER_EQUAL: GREATER_EQUAL,
+	OP_LESS:          LESS,
+	OP_LESS_EQUAL:    LESS_EQUAL,
+	OP_ADD:           PLUS,
+	OP_SUBTRACT:      MINUS,
+	OP_MULTIPLY:      STAR,
+	OP_DIVIDE:        SLASH,
+	OP_FLOOR_DIVIDE:  TILDE_SLASH,
+	OP_MODULO:        PERCENT,
+	OP_POWER:         STAR_STAR,
+	OP_BIT_AND:       AMPERSAND,
+	OP_BIT_OR:        PIPE,
+	OP_BIT_XOR:       CARET,
+	OP_SHIFT_LEFT:    LESS_LESS,
+	OP_SHIFT_RIGHT:   GREATER_GREATER,
+}
+
+type vmFunction struct {
+	name         string
+	arity        int
+	upvalueCount int
+	chunk        Chunk
+}
+
+func (f *vmFunction) String() string {
+	if f.name == "" {
+		return "<script>"
+	}
+	return "<fn " + f.name + ">"
+}
+
+// vmUpvalue points at a stack slot while the captured variable is still
+// alive on the stack and owns the value once the slot is popped.
+type vmUpvalue struct {
+	slot   int
+	closed Value
+	next   *vmUpvalue
+}
+
+type vmClosure struct {
+	function *vmFunction
+	upvalues []*vmUpvalue
+}
+
+func (c *vmClosure) String() string {
+	return c.function.String()
+}
+
+type vmClass struct {
+	name    string
+	methods map[string]*vmClosure
+}
+
+func (c *vmClass) String() string {
+	return "<class " + c.name + ">"
+}
+
+type vmInstance struct {
+	class  *vmClass
+	fields map[string]Value
+}
+
+func (o *vmInstance) String() string {
+	return "<" + o.class.name + " instance>"
+}
+
+type vmBoundMethod struct {
+	receiver Value
+	method   *vmClosure
+}
+
+func (b *vmBoundMethod) String() string {
+	return b.method.String()
+}
+
+type callFrame struct {
+	closure *vmClosure
+	ip      int
+	slots   int
+}
+
+type stackVM struct {
+	frames       []callFrame
+	stack        []Value
+	globals      map[string]Value
+	openUpvalues *vmUpvalue
+	stdout       io.Writer
+}
+
+func newStackVM() *stackVM {
+	vm := &stackVM{
+		stack:   make([]Value, 0, 256),
+		globals: map[string]Value{},
+		stdout:  os.Stdout,
+	}
+	vm.globals["clock"] = ObjectValue(clock)
+	vm.globals["range"] = ObjectValue(rangeFunction)
+	return vm
+}
+
+func (vm *stackVM) interpret(function *vmFunction) (Value, error) {
+	closure := &vmClosure{function: function}
+	result, err := vm.invoke(ObjectValue(closure))
+	if err != nil {
+		vm.frames = vm.frames[:0]
+		vm.stack = vm.stack[:0]
+		vm.openUpvalues = nil
+	}
+	return result, err
+}
+
+// run executes instructions until the number of active frames drops back to
+// base.
+func (vm *stackVM) run(base int) error {
+	frame := &vm.frames[len(vm.frames)-1]
+	code := frame.closure.function.chunk.Code
+	constants := frame.closure.function.chunk.Constants
+
+	readByte := func() byte {
+		b := code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() int {
+		frame.ip += 2
+		return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+	}
+	readString := func() string {
+		return constants[readShort()].AsString()
+	}
+	loadFrame := func() {
+		frame = &vm.frames[len(vm.frames)-1]
+		code = frame.closure.function.chunk.Code
+		constants = frame.closure.function.chunk.Constants
+	}
+
+	for {
+		op := OpCode(readByte())
+		switch op {
+		case OP_CONSTANT:
+			vm.push(constants[readShort()])
+		case OP_NIL:
+			vm.push(NilValue)
+		case OP_TRUE:
+			vm.push(BoolValue(true))
+		case OP_FALSE:
+			vm.push(BoolValue(false))
+		case OP_POP:
+			vm.pop()
+		case OP_DUP:
+			vm.push(vm.peek(0))
+		case OP_DUP2:
+			vm.push(vm.peek(1))
+			vm.push(vm.peek(1))
+		case OP_GET_LOCAL:
+			vm.push(vm.stack[frame.slots+int(readByte())])
+		case OP_SET_LOCAL:
+			vm.stack[frame.slots+int(readByte())] = vm.peek(0)
+		case OP_GET_GLOBAL:
+			name := readString()
+			value, ok := vm.globals[name]
+			if !ok {
+				return vm.error(fmt.Sprintf("Undefined Variable '%v'.", name))
+			}
+			vm.push(value)
+		case OP_DEFINE_GLOBAL:
+			vm.globals[readString()] = vm.pop()
+		case OP_SET_GLOBAL:
+			name := readString()
+			if _, ok := vm.globals[name]; !ok {
+				return vm.error(fmt.Sprintf("Undefined Variable '%v'.", name))
+			}
+			vm.globals[name] = vm.peek(0)
+		case OP_GET_UPVALUE:
+			vm.push(vm.getUpvalue(frame.closure.upvalues[readByte()]))
+		case OP_SET_UPVALUE:
+			vm.setUpvalue(frame.closure.upvalues[readByte()], vm.peek(0))
+		case OP_GET_PROPERTY:
+			name := readString()
+			instance, ok := vm.peek(0).AsObject().(*vmInstance)
+			if !ok {
+				method, err := getMethod(vm.peek(0), name)
+				if err != nil {
+					return vm.error(err.Error())
+				}
+				vm.pop()
+				vm.push(ObjectValue(method))
+				break
+			}
+			if value, ok := instance.fields[name]; ok {
+				vm.pop()
+				vm.push(value)
+				break
+			}
+			if err := vm.bindMethod(instance.class, name); err != nil {
+				return err
+			}
+		case OP_SET_PROPERTY:
+			instance, ok := vm.peek(1).AsObject().(*vmInstance)
+			if !ok {
+				return vm.error("Only instances have fields")
+			}
+			instance.fields[readString()] = vm.peek(0)
+			value := vm.pop()
+			vm.pop()
+			vm.push(value)
+		case OP_GET_SUPER:
+			name := readString()
+			superclass := vm.pop().AsObject().(*vmClass)
+			if err := vm.bindMethod(superclass, name); err != nil {
+				return err
+			}
+		case OP_EQUAL, OP_NOT_EQUAL, OP_GREATER, OP_GREATER_EQUAL, OP_LESS, OP_LESS_EQUAL,
+			OP_ADD, OP_SUBTRACT, OP_MULTIPLY, OP_DIVIDE, OP_FLOOR_DIVIDE, OP_MODULO, OP_POWER,
+			OP_BIT_AND, OP_BIT_OR, OP_BIT_XOR, OP_SHIFT_LEFT, OP_SHIFT_RIGHT:
+			b := vm.pop()
+			a := vm.pop()
+			result, err := binaryOp(binaryOpTokens[op], a, b)
+			if err != nil {
+				return vm.error(err.Error())
+			}
+			vm.push(result)
+		case OP_NOT:
+			vm.push(BoolValue(!vm.pop().IsTruthy()))
+		case OP_NEGATE, OP_BIT_NOT:
+			operator := MINUS
+			if op == OP_BIT_NOT {
+				operator = TILDE
+			}
+			result, err := unaryOp(operator, vm.peek(0))
+			if err != nil {
+				return vm.error(err.Error())
+			}
+			vm.pop()
+			vm.push(result)
+		case OP_STRINGIFY:
+			text, err := vm.stringify(vm.peek(0))
+			// toString runs on the same stack and may have grown vm.frames
+			loadFrame()
+			if err != nil {
+				return err
+			}
+			vm.pop()
+			vm.push(ObjectValue(text))
+		case OP_PRINT:
+			text, err := vm.stringify(vm.peek(0))
+			// toString runs on the same stack and may have grown vm.frames
+			loadFrame()
+			if err != nil {
+				return err
+			}
+			vm.pop()
+			if _, err := fmt.Fprintln(vm.stdout, text); err != nil {
+				return err
+			}
+		case OP_JUMP:
+			offset := readShort()
+			frame.ip += offset
+		case OP_JUMP_IF_FALSE:
+			offset := readShort()
+			if !vm.peek(0).IsTruthy() {
+				frame.ip += offset
+			}
+		case OP_LOOP:
+			offset := readShort()
+			frame.ip -= offset
+		case OP_CALL:
+			argCount := int(readByte())
+			if err := vm.callValue(vm.peek(argCount), argCount); err != nil {
+				return err
+			}
+			loadFrame()
+		case OP_CLOSURE:
+			function := constants[readShort()].AsObject().(*vmFunction)
+			closure := &vmClosure{function, make([]*vmUpvalue, function.upvalueCount)}
+			for idx := range closure.upvalues {
+				isLocal := readByte() == 1
+				index := int(readByte())
+				if isLocal {
+					closure.upvalues[idx] = vm.captureUpvalue(frame.slots + index)
+				} else {
+					closure.upvalues[idx] = frame.closure.upvalues[index]
+				}
+			}
+			vm.push(ObjectValue(closure))
+		case OP_CLOSE_UPVALUE:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case OP_RETURN:
+			result := vm.pop()
+			vm.closeUpvalues(frame.slots)
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:frame.slots]
+			vm.push(result)
+			if len(vm.frames) == base {
+				return nil
+			}
+			loadFrame()
+		case OP_LIST:
+			count := readShort()
+			elements := make([]Value, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(ObjectValue(NewList(elements...)))
+		case OP_MAP:
+			count := readShort()
+			m := NewMap()
+			for idx := len(vm.stack) - 2*count; idx < len(vm.stack); idx += 2 {
+				if err := m.Set(vm.stack[idx], vm.stack[idx+1]); err != nil {
+					return vm.error(err.Error())
+				}
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*count]
+			vm.push(ObjectValue(m))
+		case OP_ITER:
+			it, err := vm.iterate(vm.peek(0))
+			// iter() runs on the same stack and may have grown vm.frames
+			loadFrame()
+			if err != nil {
+				return err
+			}
+			vm.pop()
+			vm.push(ObjectValue(it))
+		case OP_FOR_ITER:
+			offset := readShort()
+			value, ok, err := vm.peek(0).AsObject().(iterator).next()
+			loadFrame()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				frame.ip += offset
+				break
+			}
+			vm.push(value)
+		case OP_GET_INDEX:
+			value, err := getIndex(vm.peek(1), vm.peek(0))
+			if err != nil {
+				return vm.error(err.Error())
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2]
+			vm.push(value)
+		case OP_SET_INDEX:
+			if err := setIndex(vm.peek(2), vm.peek(1), vm.peek(0)); err != nil {
+				return vm.error(err.Error())
+			}
+			value := vm.pop()
+			vm.stack = vm.stack[:len(vm.stack)-2]
+			vm.push(value)
+		case OP_CLASS:
+			vm.push(ObjectValue(&vmClass{readString(), map[string]*vmClosure{}}))
+		case OP_INHERIT:
+			superclass, ok := vm.peek(1).AsObject().(*vmClass)
+			if !ok {
+				return vm.error("Superclass must be a class")
+			}
+			subclass := vm.peek(0).AsObject().(*vmClass)
+			for name, method := range superclass.methods {
+				subclass.methods[name] = method
+			}
+			vm.pop()
+		case OP_METHOD:
+			method := vm.peek(0).AsObject().(*vmClosure)
+			class := vm.peek(1).AsObject().(*vmClass)
+			class.methods[readString()] = method
+			vm.pop()
+		default:
+			return vm.error(fmt.Sprintf("Unknown opcode %v", op))
+		}
+	}
+}
+
+// invoke calls callee from go code and runs it to completion.
+func (vm *stackVM) invoke(callee Value, args ...Value) (Value, error) {
+	base := len(vm.frames)
+	vm.push(callee)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+	if err := vm.callValue(callee, len(args)); err != nil {
+		return NilValue, err
+	}
+	if len(vm.frames) > base {
+		if err := vm.run(base); err != nil {
+			return NilValue, err
+		}
+	}
+	return vm.pop(), nil
+}
+
+// iterate returns an iterator over v for a for-in loop, instances are
+// iterated through their iter() or next() method.
+func (vm *stackVM) iterate(v Value) (iterator, error) {
+	if it, ok := iterate(v); ok {
+		return it, nil
+	}
+	instance, ok := v.AsObject().(*vmInstance)
+	if !ok {
+		return nil, vm.error(notIterable)
+	}
+	if iter, ok := instance.class.methods["iter"]; ok {
+		result, err := vm.invoke(ObjectValue(&vmBoundMethod{v, iter}))
+		if err != nil {
+			return nil, err
+		}
+		if it, ok := iterate(result); ok {
+			return it, nil
+		}
+		if instance, ok = result.AsObject().(*vmInstance); !ok {
+			return nil, vm.error(notIterable)
+		}
+	}
+	next, ok := instance.class.methods["next"]
+	if !ok {
+		return nil, vm.error(notIterable)
+	}
+	bound := ObjectValue(&vmBoundMethod{ObjectValue(instance), next})
+	return methodIterator(func() (Value, error) { return vm.invoke(bound) }), nil
+}
+
+// stringify formats v like Value.String but lets instances, also inside of
+// collections, provide their own representation through a toString method.
+func (vm *stackVM) stringify(v Value) (string, error) {
+	return formatValue(v, vm.toString)
+}
+
+func (vm *stackVM) toString(v Value) (string, error) {
+	instance, ok := v.AsObject().(*vmInstance)
+	if !ok {
+		return v.String(), nil
+	}
+	method, ok := instance.class.methods["toString"]
+	if !ok {
+		return v.String(), nil
+	}
+	result, err := vm.invoke(ObjectValue(&vmBoundMethod{v, method}))
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+func (vm *stackVM) callValue(callee Value, argCount int) error {
+	switch callee := callee.AsObject().(type) {
+	case *vmClosure:
+		return vm.call(callee, argCount)
+	case *NativeFunction:
+		if callee.arity >= 0 && argCount != callee.arity {
+			return vm.error(fmt.Sprintf("Expected %v arguments but got %v", callee.arity, argCount))
+		}
+		// natives may keep their arguments, so they get a copy instead of a
+		// window onto the stack
+		result, err := callee.fn(slices.Clone(vm.stack[len(vm.stack)-argCount:]))
+		if err != nil {
+			return vm.error(err.Error())
+		}
+		vm.stack = vm.stack[:len(vm.stack)-argCount-1]
+		vm.push(result)
+		return nil
+	case *vmClass:
+		vm.stack[len(vm.stack)-argCount-1] = ObjectValue(&vmInstance{callee, map[string]Value{}})
+		if initializer, ok := callee.methods["init"]; ok {
+			return vm.call(initializer, argCount)
+		}
+		if argCount != 0 {
+			return vm.error(fmt.Sprintf("Expected %v arguments but got %v", 0, argCount))
+		}
+		return nil
+	case *vmBoundMethod:
+		vm.stack[len(vm.stack)-argCount-1] = callee.receiver
+		return vm.call(callee.method, argCount)
+	}
+	return vm.error("Can only call functions and classes")
+}
+
+func (vm *stackVM) call(closure *vmClosure, argCount int) error {
+	if argCount != closure.function.arity {
+		return vm.error(fmt.Sprintf("Expected %v arguments but got %v", closure.function.arity, argCount))
+	}
+	if len(vm.frames) == maxFrames {
+		return vm.error("Stack overflow")
+	}
+	vm.frames = append(vm.frames, callFrame{closure, 0, len(vm.stack) - argCount - 1})
+	return nil
+}
+
+func (vm *stackVM) bindMethod(class *vmClass, name string) error {
+	method, ok := class.methods[name]
+	if !ok {
+		return vm.error(fmt.Sprintf("Undefined property '%v'.", name))
+	}
+	bound := &vmBoundMethod{vm.peek(0), method}
+	vm.pop()
+	vm.push(ObjectValue(bound))
+	return nil
+}
+
+func (vm *stackVM) captureUpvalue(slot int) *vmUpvalue {
+	var prev *vmUpvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		prev = upvalue
+		upvalue = upvalue.next
+	}
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+
+	created := &vmUpvalue{slot: slot, next: upvalue}
+	if prev == nil {
+		vm.openUpvalues = created
+	} else {
+		prev.next = created
+	}
+	return created
+}
+
+func (vm *stackVM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.slot]
+		upvalue.slot = -1
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+func (vm *stackVM) getUpvalue(upvalue *vmUpvalue) Value {
+	if upvalue.slot >= 0 {
+		return vm.stack[upvalue.slot]
+	}
+	return upvalue.closed
+}
+
+func (vm *stackVM) setUpvalue(upvalue *vmUpvalue, value Value) {
+	if upvalue.slot >= 0 {
+		vm.stack[upvalue.slot] = value
+	} else {
+		upvalue.closed = value
+	}
+}
+
+func (vm *stackVM) push(value Value) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *stackVM) pop() Value {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *stackVM) peek(distance int) Value {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+func (vm *stackVM) error(message string) error {
+	frame := &vm.frames[len(vm.frames)-1]
+	token := frame.closure.function.chunk.Token(frame.ip - 1)
+	return RunTimeError{token, message, vm.traceback()}
+}
+
+// traceback returns the active frames as stack frames, innermost first. It
+// is nil while only the script itself is running.
+func (vm *stackVM) traceback() []StackFrame {
+	if len(vm.frames) < 2 {
+		return nil
+	}
+	trace := make([]StackFrame, 0, len(vm.frames))
+	for idx := len(vm.frames) - 1; idx >= 0; idx-- {
+		frame := &vm.frames[idx]
+		name := frame.closure.function.name
+		if name == "" {
+			name = "script"
+		}
+		trace = append(trace, StackFrame{name, frame.closure.function.chunk.Token(frame.ip - 1).Span()})
+	}
+	return trace
+}
@@ -10,16 +10,39 @@ import (
 func main() {
 	fileArg := flag.String("file", "code.lox", "Execute a lox file")
 	replArg := flag.Bool("repl", false, "open a repl ")
+	backendArg := flag.String("backend", "tree", "Execution backend, either tree or vm")
+	colorArg := flag.Bool("color", false, "Color diagnostics")
+	diagnosticsArg := flag.String("diagnostics", "text", "Diagnostics format, either text or json")
 	flag.Parse()
 
+	var backend glox.Backend
+	switch *backendArg {
+	case "tree":
+		backend = glox.TreeWalker
+	case "vm":
+		backend = glox.Bytecode
+	default:
+		log.Fatalln("Unknown backend", *backendArg)
+	}
+
+	var format glox.DiagnosticFormat
+	switch *diagnosticsArg {
+	case "text":
+		format = glox.TextDiagnostics
+	case "json":
+		format = glox.JSONDiagnostics
+	default:
+		log.Fatalln("Unknown diagnostics format", *diagnosticsArg)
+	}
+	vm := glox.New(glox.WithBackend(backend), glox.WithColor(*colorArg), glox.WithDiagnosticFormat(format))
+
 	if *replArg {
-		glox.Repl()
+		if err := vm.Repl(); err != nil {
+			log.Fatalln("Error reading line", err)
+		}
 	} else {
-		b, err := os.ReadFile(*fileArg)
-		if err != nil {
-			log.Fatalln("Error occured while reading file", *fileArg, ":", err)
+		if err := vm.RunFile(*fileArg); err != nil {
+			os.Exit(1)
 		}
-		code := string(b)
-		glox.Run(code)
 	}
 }